@@ -0,0 +1,107 @@
+package onion
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ----------------------------------------------------
+// API key authentication
+// ----------------------------------------------------
+
+// APIKeyConfig configures APIKeyAuth.
+type APIKeyConfig struct {
+	// Lookups says where to find the key, tried in order: "header:<name>",
+	// "query:<name>" or "cookie:<name>". Defaults to "header:X-API-Key".
+	Lookups []string
+
+	// Keys is the set of accepted keys. Ignored if Validator is set.
+	Keys []string
+
+	// Validator checks a key and maps it to a principal (a user, a client
+	// app, ...). Return ok = false to reject the key.
+	Validator func(c *Context, key string) (principal interface{}, ok bool)
+
+	// ContextKey is where the principal is stored with c.Set, "principal" by
+	// default. With Keys, the principal is the key itself.
+	ContextKey string
+}
+
+// APIKeyAuth rejects requests without a valid API key with 401 and aborts.
+// The first lookup that finds a non-empty value decides, so with
+// Lookups: {"header:X-API-Key", "query:api_key"} the header wins over the query.
+//
+//	app.Use(onion.APIKeyAuth(onion.APIKeyConfig{
+//		Lookups: []string{"header:X-API-Key", "query:api_key"},
+//		Validator: func(c *onion.Context, key string) (interface{}, bool) {
+//			client, err := clients.ByKey(key)
+//			return client, err == nil
+//		},
+//	}))
+//
+// It panics on a malformed lookup, so the mistake shows up at startup.
+func APIKeyAuth(config APIKeyConfig) HandlerFunc {
+	if len(config.Lookups) == 0 {
+		config.Lookups = []string{"header:X-API-Key"}
+	}
+	if config.ContextKey == "" {
+		config.ContextKey = "principal"
+	}
+
+	type lookup struct{ source, name string }
+	lookups := make([]lookup, 0, len(config.Lookups))
+	for _, l := range config.Lookups {
+		source, name, ok := strings.Cut(l, ":")
+		if !ok || name == "" || (source != "header" && source != "query" && source != "cookie") {
+			panic(fmt.Sprintf("onion: invalid API key lookup %q", l))
+		}
+		lookups = append(lookups, lookup{source, name})
+	}
+
+	validate := config.Validator
+	if validate == nil {
+		keys := config.Keys
+		validate = func(c *Context, key string) (interface{}, bool) {
+			for _, k := range keys {
+				if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+					return key, true
+				}
+			}
+			return nil, false
+		}
+	}
+
+	return func(c *Context) {
+		key := ""
+		for _, l := range lookups {
+			switch l.source {
+			case "header":
+				key = c.Request.Header.Get(l.name)
+			case "query":
+				key = c.Request.URL.Query().Get(l.name)
+			case "cookie":
+				if cookie, err := c.Request.Cookie(l.name); err == nil {
+					key = cookie.Value
+				}
+			}
+			if key != "" {
+				break
+			}
+		}
+
+		if key == "" {
+			c.String(http.StatusUnauthorized, "Missing API key")
+			c.Abort()
+			return
+		}
+		principal, ok := validate(c, key)
+		if !ok {
+			c.String(http.StatusUnauthorized, "Invalid API key")
+			c.Abort()
+			return
+		}
+		c.Set(config.ContextKey, principal)
+	}
+}
@@ -0,0 +1,70 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestAPIKeyAuth ensures keys are read from the header or the query, with the header first.
+func TestAPIKeyAuth(t *testing.T) {
+	app := New()
+	app.Use(APIKeyAuth(APIKeyConfig{
+		Lookups: []string{"header:X-API-Key", "query:api_key"},
+		Keys:    []string{"secret"},
+	}))
+	app.handle("GET", "/data", func(c *Context) {
+		key, _ := c.Get("principal")
+		c.String(http.StatusOK, key.(string))
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		code   int
+	}{
+		{"header", "/data", "secret", http.StatusOK},
+		{"query", "/data?api_key=secret", "", http.StatusOK},
+		{"header wins", "/data?api_key=secret", "wrong", http.StatusUnauthorized},
+		{"missing", "/data", "", http.StatusUnauthorized},
+		{"invalid", "/data?api_key=nope", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		if tt.header != "" {
+			req.Header.Set("X-API-Key", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected status code %d, got %d", tt.name, tt.code, rec.Code)
+		}
+	}
+}
+
+// TestAPIKeyAuthValidator ensures the validator's principal is stored on the context.
+func TestAPIKeyAuthValidator(t *testing.T) {
+	type client struct{ Name string }
+
+	app := New()
+	app.Use(APIKeyAuth(APIKeyConfig{
+		Lookups: []string{"cookie:session_key"},
+		Validator: func(c *Context, key string) (interface{}, bool) {
+			return client{Name: "acme"}, key == "k1"
+		},
+		ContextKey: "client",
+	}))
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, MustGet[client](c, "client").Name)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session_key", Value: "k1"})
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "acme" {
+		t.Errorf("Expected principal 'acme', got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,206 @@
+package onion
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Audit log (request and response bodies)
+// ----------------------------------------------------
+
+// DefaultAuditBodySize is the default cap on bodies logged by AuditLog.
+const DefaultAuditBodySize = 4 << 10 // 4KB
+
+// auditRedacted replaces redacted values.
+const auditRedacted = "[REDACTED]"
+
+// AuditConfig configures AuditLog.
+type AuditConfig struct {
+	// Output receives one line per request. Defaults to the app's logger.
+	Output LogPrinter
+
+	// Routes lists the route patterns to audit, e.g. "/users/:id". Empty
+	// means every route.
+	Routes []string
+
+	// Redact lists the JSON keys and form fields whose values are replaced
+	// by "[REDACTED]", at any depth, ignoring case. Defaults to "password",
+	// "token", "secret" and "authorization".
+	Redact []string
+
+	// MaxBodySize caps each logged body. Bigger bodies are replaced by a
+	// placeholder. Defaults to DefaultAuditBodySize.
+	MaxBodySize int
+
+	// ContentTypes lists the body types that are logged. Defaults to
+	// application/json and application/x-www-form-urlencoded, the types
+	// redaction understands; others are logged as "[<type> omitted]".
+	ContentTypes []string
+}
+
+// AuditLog logs the request and response bodies of the configured routes,
+// with sensitive fields redacted, once the handler is done:
+//
+//	audit POST /login 200 request={"password":"[REDACTED]","user":"ann"} response={"ok":true}
+//
+// The handler reads the request body as usual, while at most MaxBodySize+1
+// bytes of it are copied for the log. Only bodies of the allowed content
+// types are logged, and a body that can't be parsed (and so can't be
+// redacted) is omitted rather than logged as is.
+func AuditLog(config AuditConfig) HandlerFunc {
+	if len(config.Redact) == 0 {
+		config.Redact = []string{"password", "token", "secret", "authorization"}
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = DefaultAuditBodySize
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = []string{"application/json", "application/x-www-form-urlencoded"}
+	}
+	routes := toSet(config.Routes)
+	redact := make(map[string]bool, len(config.Redact))
+	for _, k := range config.Redact {
+		redact[strings.ToLower(k)] = true
+	}
+
+	return func(c *Context) {
+		if len(routes) > 0 && !routes[c.route.pattern] {
+			return
+		}
+		// Only copy the bodies that will be logged, and no more than
+		// MaxBodySize+1 bytes of them: the handler still streams the rest
+		request := "-"
+		contentType := c.Request.Header.Get("Content-Type")
+		var captured *cappedBuffer
+		var body io.Reader
+		if config.allows(contentType) && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			captured = &cappedBuffer{limit: config.MaxBodySize + 1}
+			body = io.TeeReader(c.Request.Body, captured)
+			c.Request.Body = struct {
+				io.Reader
+				io.Closer
+			}{body, c.Request.Body}
+		} else if c.Request.ContentLength != 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			request = omittedBody(contentType)
+		}
+
+		orig := c.Response
+		rec := &recordingWriter{ResponseWriter: orig, limit: config.MaxBodySize}
+		c.Response = rec
+		defer func() { c.Response = orig }()
+
+		c.Next()
+
+		if captured != nil {
+			// Read what the handler left, up to the cap, so it's logged too
+			if n := captured.limit - captured.Len(); n > 0 {
+				io.CopyN(io.Discard, body, int64(n))
+			}
+			request = config.body(contentType, captured.Bytes(), false, redact)
+		}
+		response := config.body(orig.Header().Get("Content-Type"), rec.body.Bytes(), rec.truncated, redact)
+		out := config.Output
+		if out == nil {
+			out = c.app.logger
+		}
+		out.Printf("audit %s %s %d request=%s response=%s",
+			c.Request.Method, c.Request.URL.Path, c.writer.status, request, response)
+	}
+}
+
+// allows reports whether bodies of contentType are logged.
+func (config *AuditConfig) allows(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return containsFold(config.ContentTypes, mt)
+}
+
+// body renders a body for the audit log: redacted, or a placeholder.
+func (config *AuditConfig) body(contentType string, data []byte, truncated bool, redact map[string]bool) string {
+	if len(data) == 0 && !truncated {
+		return "-"
+	}
+	if truncated || len(data) > config.MaxBodySize {
+		return "[body over " + strconv.Itoa(config.MaxBodySize) + " bytes omitted]"
+	}
+	if contentType == "" {
+		// as net/http does for responses without one
+		contentType = http.DetectContentType(data)
+	}
+	if !config.allows(contentType) {
+		return omittedBody(contentType)
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mt == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			break
+		}
+		for k := range form {
+			if redact[strings.ToLower(k)] {
+				form[k] = []string{auditRedacted}
+			}
+		}
+		return form.Encode()
+	case mt == "application/json" || strings.HasSuffix(mt, "+json"):
+		var v interface{}
+		if json.Unmarshal(data, &v) != nil {
+			break
+		}
+		out, err := json.Marshal(redactJSON(v, redact))
+		if err != nil {
+			break
+		}
+		return string(out)
+	}
+	return "[unparseable body omitted]"
+}
+
+// omittedBody is the placeholder for bodies of types that aren't logged.
+func omittedBody(contentType string) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	if mt == "" {
+		mt = "untyped"
+	}
+	return "[" + mt + " body omitted]"
+}
+
+// cappedBuffer keeps the first limit bytes written to it and drops the rest.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// redactJSON replaces the values of redacted keys, at any depth.
+func redactJSON(v interface{}, redact map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range v {
+			if redact[strings.ToLower(k)] {
+				v[k] = auditRedacted
+			} else {
+				v[k] = redactJSON(inner, redact)
+			}
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactJSON(inner, redact)
+		}
+	}
+	return v
+}
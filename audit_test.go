@@ -0,0 +1,105 @@
+package onion
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestAuditLog ensures configured fields are redacted in both bodies, at any depth.
+func TestAuditLog(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.Use(AuditLog(AuditConfig{Routes: []string{"/login", "/profile"}, Redact: []string{"password", "token"}}))
+	app.POST("/login", func(c *Context) {
+		var body map[string]string
+		c.BindJSON(&body)
+		c.JSON(http.StatusOK, map[string]interface{}{"user": body["user"], "session": map[string]string{"token": "t0k3n"}})
+	})
+	app.POST("/profile", func(c *Context) {
+		c.String(http.StatusOK, "saved")
+	})
+	app.POST("/other", func(c *Context) {})
+
+	send := func(path, contentType, body string) {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		app.mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	send("/login", "application/json", `{"user":"ann","password":"hunter2"}`)
+	send("/profile", "application/x-www-form-urlencoded", "name=ann&Password=hunter2")
+	send("/other", "application/json", `{"password":"hunter2"}`)
+
+	out := logs.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "t0k3n") {
+		t.Errorf("Expected secrets to be redacted, got '%s'", out)
+	}
+	want := `audit POST /login 200 request={"password":"[REDACTED]","user":"ann"} response={"session":{"token":"[REDACTED]"},"user":"ann"}`
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected '%s', got '%s'", want, out)
+	}
+	if !strings.Contains(out, "audit POST /profile 200 request=Password=%5BREDACTED%5D&name=ann response=[text/plain body omitted]") {
+		t.Errorf("Expected the redacted form and an omitted text body, got '%s'", out)
+	}
+	if strings.Contains(out, "/other") {
+		t.Errorf("Expected routes not configured to be skipped, got '%s'", out)
+	}
+}
+
+// TestAuditLogSkipsOtherTypes ensures bodies of other types aren't buffered and streaming still works.
+func TestAuditLogSkipsOtherTypes(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.Use(AuditLog(AuditConfig{}))
+	app.POST("/upload", func(c *Context) {
+		if c.rawBody != nil {
+			t.Error("Expected the request body not to be cached")
+		}
+		c.Response.Header().Set("Content-Type", "text/event-stream")
+		c.Response.Write([]byte("data: 1\n\n"))
+		c.Flush()
+	})
+
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader("raw bytes"))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if !rec.Flushed {
+		t.Error("Expected the response to be flushed")
+	}
+	want := "audit POST /upload 200 request=[application/octet-stream body omitted] response=[text/event-stream body omitted]"
+	if logs.String() != want {
+		t.Errorf("Expected '%s', got '%s'", want, logs.String())
+	}
+}
+
+// TestAuditLogLargeBody ensures a body over MaxBodySize reaches the handler whole while the log gets a placeholder.
+func TestAuditLogLargeBody(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.Use(AuditLog(AuditConfig{MaxBodySize: 16}))
+	var read int
+	app.POST("/import", func(c *Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		read = len(data)
+		if c.rawBody != nil {
+			t.Error("Expected the request body not to be cached")
+		}
+	})
+
+	body := `{"rows":"` + strings.Repeat("x", 64<<10) + `"}`
+	req := httptest.NewRequest("POST", "/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	app.mux.ServeHTTP(httptest.NewRecorder(), req)
+	if read != len(body) {
+		t.Errorf("Expected the handler to read %d bytes, got %d", len(body), read)
+	}
+	if want := "request=[body over 16 bytes omitted]"; !strings.Contains(logs.String(), want) {
+		t.Errorf("Expected '%s' in the log, got '%s'", want, logs.String())
+	}
+}
@@ -0,0 +1,106 @@
+package onion
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// ----------------------------------------------------
+// Startup banner
+// ----------------------------------------------------
+
+// Banner toggles the route table printed when the server starts. It is on by
+// default; with it off only the "running on" line is logged.
+func (a *App) Banner(on bool) {
+	a.banner = on
+}
+
+// printBanner logs the bind address and, if enabled, every registered route.
+func (a *App) printBanner(addr string) {
+	if !a.banner {
+		a.logger.Printf("Onion server running on %s", addr)
+		return
+	}
+
+	routes := a.namedRoutes()
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	for _, r := range routes {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", r.key.method, r.key.host+r.key.pattern, r.handler)
+	}
+	tw.Flush()
+
+	a.logger.Printf("Onion server running on %s", addr)
+	a.logger.Printf("%d route(s) registered:", len(routes))
+	for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		if line != "" {
+			a.logger.Printf("%s", line)
+		}
+	}
+}
+
+// namedRoute is a route table key with its handler's name.
+type namedRoute struct {
+	key     routeKey
+	handler string
+}
+
+// namedRoutes snapshots the route table, sorted like sortedRouteKeys. It
+// holds the lock throughout, since AddRoute may write to the table while
+// the server runs.
+func (a *App) namedRoutes() []namedRoute {
+	a.routesMu.RLock()
+	defer a.routesMu.RUnlock()
+
+	keys := sortedRouteKeys(a.routes)
+	routes := make([]namedRoute, len(keys))
+	for i, k := range keys {
+		routes[i] = namedRoute{key: k, handler: handlerName(a.routes[k].handler)}
+	}
+	return routes
+}
+
+// sortedRouteKeys lists the keys of routes by host, pattern, method, scheme,
+// then version, so the order is the same from run to run.
+func sortedRouteKeys(routes map[routeKey]*routeEntry) []routeKey {
+	keys := make([]routeKey, 0, len(routes))
+	for k := range routes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].host != keys[j].host {
+			return keys[i].host < keys[j].host
+		}
+		if keys[i].pattern != keys[j].pattern {
+			return keys[i].pattern < keys[j].pattern
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		if keys[i].scheme != keys[j].scheme {
+			return keys[i].scheme < keys[j].scheme
+		}
+		if c := compareVersions(keys[i].version, keys[j].version); c != 0 {
+			return c < 0
+		}
+		return keys[i].version < keys[j].version
+	})
+	return keys
+}
+
+// handlerName resolves a function's name, e.g. "main.GetBook".
+func handlerName(h HandlerFunc) string {
+	if h == nil {
+		return "<nil>"
+	}
+	if fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return "<unknown>"
+}
@@ -0,0 +1,82 @@
+package onion
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func bannerTestHandler(c *Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+// TestBanner ensures the route table is printed through the injected logger.
+func TestBanner(t *testing.T) {
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.UseRoutes(NewGroup("books").
+		GET("/:bookId", bannerTestHandler).
+		POST("", bannerTestHandler).
+		Routes())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.RunListener(ln)
+	time.Sleep(50 * time.Millisecond)
+	app.Shutdown(context.Background())
+
+	out := logger.String()
+	if !strings.Contains(out, "running on "+ln.Addr().String()) {
+		t.Errorf("Expected the bind address in the banner, got:\n%s", out)
+	}
+	if !strings.Contains(out, "2 route(s)") {
+		t.Errorf("Expected the route count in the banner, got:\n%s", out)
+	}
+	for _, want := range []string{"GET", "/books/:bookId", "POST", "/books", "onion.bannerTestHandler"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected '%s' in the banner, got:\n%s", want, out)
+		}
+	}
+}
+
+// TestSortedRouteKeys ensures routes differing only by scheme or version are listed in a stable order.
+func TestSortedRouteKeys(t *testing.T) {
+	want := []routeKey{
+		{method: "GET", pattern: "/books"},
+		{method: "GET", pattern: "/books", version: "application/vnd.books.v2+json"},
+		{method: "GET", pattern: "/books", version: "application/vnd.books.v10+json"},
+		{method: "GET", pattern: "/books", scheme: "https"},
+		{method: "POST", pattern: "/books"},
+	}
+	routes := make(map[routeKey]*routeEntry)
+	for _, k := range want {
+		routes[k] = &routeEntry{}
+	}
+	for i := 0; i < 10; i++ { // map order is random: repeat to catch unstable sorts
+		if got := sortedRouteKeys(routes); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+// TestBannerOff ensures only the address line is logged when the banner is disabled.
+func TestBannerOff(t *testing.T) {
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.Banner(false)
+	app.Handle("GET", "/", bannerTestHandler)
+
+	app.printBanner("127.0.0.1:3333")
+
+	if out := logger.String(); out != "Onion server running on 127.0.0.1:3333" {
+		t.Errorf("Expected a single line, got:\n%s", out)
+	}
+}
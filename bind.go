@@ -0,0 +1,479 @@
+package onion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------
+// Binding (request body => Go values)
+// ----------------------------------------------------
+
+// DefaultMaxJSONArrayElements is the element cap used by BindJSONArray.
+const DefaultMaxJSONArrayElements = 1000
+
+// MaxNDJSONLineSize caps each record read by DecodeNDJSON, so a body without
+// newlines can't make it buffer without bound.
+const MaxNDJSONLineSize = 1 << 20 // 1MB
+
+// MaxJSONArrayElements sets how many elements BindJSONArray accepts.
+func (a *App) MaxJSONArrayElements(n int) {
+	a.maxArrayElements = n
+}
+
+// BindJSON decodes the JSON body into v, which may be a pointer to a struct,
+// a map or a slice (*[]T for batch endpoints). Failures are HTTPErrors (400,
+// or 413 past App.MaxBodySize), so they can be handed straight to c.Error:
+//
+//	if err := c.BindJSON(&book); err != nil {
+//		c.Error(err)
+//		return
+//	}
+func (c *Context) BindJSON(v interface{}) error {
+	if c.Request.Body == nil {
+		return NewHTTPError(http.StatusBadRequest, "empty request body")
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(v); err != nil {
+		return bindError(err)
+	}
+	return nil
+}
+
+// RespondOption customizes BindJSONAndRespond.
+type RespondOption func(*respondConfig)
+
+type respondConfig struct {
+	status int
+}
+
+// WithStatus sets the success status of BindJSONAndRespond, e.g.
+// http.StatusCreated for create handlers. The default is 200.
+func WithStatus(code int) RespondOption {
+	return func(rc *respondConfig) {
+		rc.status = code
+	}
+}
+
+// BindJSONAndRespond is the usual create/update handler in one call: it
+// binds the JSON body into v, runs process and renders its result as JSON.
+// A bind or process error goes to the error handler instead.
+//
+//	group.POST("/books", func(c *onion.Context) {
+//		var book Book
+//		c.BindJSONAndRespond(&book, func() (interface{}, error) {
+//			return books.Create(book)
+//		}, onion.WithStatus(http.StatusCreated))
+//	})
+func (c *Context) BindJSONAndRespond(v interface{}, process func() (interface{}, error), opts ...RespondOption) {
+	rc := respondConfig{status: http.StatusOK}
+	for _, opt := range opts {
+		opt(&rc)
+	}
+
+	if err := c.BindJSON(v); err != nil {
+		c.Error(err)
+		return
+	}
+	result, err := process()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(rc.status, result)
+}
+
+// RequireFields decodes a JSON object body and checks that each named
+// top-level key is present and not null, for dynamic endpoints that don't
+// warrant a struct. It returns the parsed object, or a 400 HTTPError listing
+// every missing field, e.g. "missing required fields: email, name".
+func (c *Context) RequireFields(fields ...string) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := c.BindJSON(&obj); err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, NewHTTPError(http.StatusBadRequest, "expected a JSON object")
+	}
+
+	var missing []string
+	for _, f := range fields {
+		if obj[f] == nil {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return obj, NewHTTPError(http.StatusBadRequest, "missing required fields: "+strings.Join(missing, ", "))
+	}
+	return obj, nil
+}
+
+// BindJSONArray decodes a JSON array into v (a *[]T) one element at a time,
+// so a huge array is rejected (413) as soon as it passes the app's element
+// cap instead of being decoded in full. Decode errors name the failing element.
+func (c *Context) BindJSONArray(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("onion: BindJSONArray needs a pointer to a slice, got %T", v)
+	}
+	if c.Request.Body == nil {
+		return NewHTTPError(http.StatusBadRequest, "empty request body")
+	}
+
+	max := DefaultMaxJSONArrayElements
+	if c.app != nil && c.app.maxArrayElements > 0 {
+		max = c.app.maxArrayElements
+	}
+
+	dec := json.NewDecoder(c.Request.Body)
+	tok, err := dec.Token()
+	if err != nil {
+		return bindError(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return NewHTTPError(http.StatusBadRequest, "expected a JSON array")
+	}
+
+	slice := reflect.MakeSlice(rv.Elem().Type(), 0, 0)
+	elemType := slice.Type().Elem()
+	for i := 0; dec.More(); i++ {
+		if i >= max {
+			return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many elements (max %d)", max))
+		}
+		elem := reflect.New(elemType)
+		if err := dec.Decode(elem.Interface()); err != nil {
+			he := bindError(err)
+			he.Message = fmt.Sprintf("element %d: %s", i, he.Message)
+			return he
+		}
+		slice = reflect.Append(slice, elem.Elem())
+	}
+	if _, err := dec.Token(); err != nil {
+		return bindError(err)
+	}
+
+	rv.Elem().Set(slice)
+	return nil
+}
+
+// BindHeader fills the fields of the struct v points to from request
+// headers named by `header` tags, converting to the field type (strings,
+// bools, ints, uints, floats, time.Duration, time.Time in the layout of a
+// `time_format` tag or RFC 3339, types implementing encoding.TextUnmarshaler,
+// or slices of those for repeated headers). Names are canonicalized, so
+// `header:"x-page-size"` works too.
+// Missing headers leave the field untouched; a value that doesn't convert is
+// a 400 HTTPError naming the header.
+//
+//	var h struct {
+//		Version  string `header:"X-Api-Version"`
+//		PageSize int    `header:"X-Page-Size"`
+//	}
+//	if err := c.BindHeader(&h); err != nil { ... }
+func (c *Context) BindHeader(v interface{}) error {
+	return bindTagged(v, "header", "header", true, func(name string) []string {
+		return c.Request.Header.Values(http.CanonicalHeaderKey(name))
+	})
+}
+
+// BindQuery fills the fields of the struct v points to from query
+// parameters named by `query` tags, with the same conversions and errors as
+// BindHeader. A field can also list older names in an `alias` tag and a
+// fallback with ",default=":
+//
+//	var q struct {
+//		Term  string `query:"q,default=*" alias:"search,term"`
+//		Limit int    `query:"limit,default=20"`
+//	}
+//
+// The first of q, search, term present in the query wins; if none is, Term
+// is "*". A parameter present but empty (?q=) counts as present.
+func (c *Context) BindQuery(v interface{}) error {
+	query := c.Request.URL.Query()
+	return bindTagged(v, "query", "query parameter", true, func(name string) []string {
+		return query[name]
+	})
+}
+
+// BindSource is a part of the request BindAll reads from.
+type BindSource int
+
+const (
+	BindParams BindSource = iota // path params, by `param` tag
+	BindBody                     // the JSON body, by `json` tag
+	BindQuery                    // query parameters, by `query` tag
+	BindHeader                   // headers, by `header` tag
+)
+
+// defaultBindOrder is BindAll's priority unless App.BindOrder changes it.
+var defaultBindOrder = []BindSource{BindParams, BindBody, BindQuery, BindHeader}
+
+// BindOrder sets the sources BindAll reads and their priority: when a field
+// is found in several, the one listed first wins. The default is
+// BindParams, BindBody, BindQuery, BindHeader. Passing nil restores it.
+func (a *App) BindOrder(order []BindSource) {
+	a.bindOrder = order
+}
+
+// BindAll fills the struct v points to from every source in App.BindOrder,
+// so one struct can describe a whole request:
+//
+//	var req struct {
+//		ID    int    `param:"id"`
+//		Name  string `json:"name" query:"name"`
+//		Trace string `header:"X-Trace"`
+//	}
+//
+// Tag defaults apply only when no source has the field. An empty body is
+// skipped. Errors are HTTPErrors, as with BindJSON and BindQuery.
+func (c *Context) BindAll(v interface{}) error {
+	order := c.app.bindOrder
+	if order == nil {
+		order = defaultBindOrder
+	}
+	// Defaults first, then the sources from the lowest priority up, each
+	// overwriting what it has
+	none := func(string) []string { return nil }
+	for tag, kind := range map[string]string{"param": "path param", "query": "query parameter", "header": "header"} {
+		if err := bindTagged(v, tag, kind, true, none); err != nil {
+			return err
+		}
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		var err error
+		switch order[i] {
+		case BindParams:
+			err = bindTagged(v, "param", "path param", false, func(name string) []string {
+				if !hasParam(c.params, name) {
+					return nil
+				}
+				return []string{c.Param(name)}
+			})
+		case BindBody:
+			if c.Request.Body != nil && c.Request.Body != http.NoBody && c.Request.ContentLength != 0 {
+				err = c.BindJSON(v)
+			}
+		case BindQuery:
+			query := c.Request.URL.Query()
+			err = bindTagged(v, "query", "query parameter", false, func(name string) []string {
+				return query[name]
+			})
+		case BindHeader:
+			err = bindTagged(v, "header", "header", false, func(name string) []string {
+				return c.Request.Header.Values(http.CanonicalHeaderKey(name))
+			})
+		default:
+			err = fmt.Errorf("onion: unknown bind source %d", order[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindTagged sets the fields of the struct v points to from values looked up
+// by the field's tag, then its `alias` names, then (with defaults) its
+// ",default=" value. kind names the source in error messages.
+func bindTagged(v interface{}, tag, kind string, defaults bool, lookup func(name string) []string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("onion: binding needs a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		name, def, hasDefault := parseBindTag(f.Tag.Get(tag))
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		names := []string{name}
+		if aliases := f.Tag.Get("alias"); aliases != "" {
+			names = append(names, strings.Split(aliases, ",")...)
+		}
+		var vals []string
+		for _, n := range names {
+			if vals = lookup(n); len(vals) > 0 {
+				name = n
+				break
+			}
+		}
+		if len(vals) == 0 {
+			if !defaults || !hasDefault {
+				continue
+			}
+			vals = []string{def}
+		}
+		if err := setField(rv.Field(i), vals, f.Tag.Get("time_format")); err != nil {
+			return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s %s: %v", kind, name, err))
+		}
+	}
+	return nil
+}
+
+// parseBindTag splits a tag like "q,default=*" into the name and default.
+// The default runs to the end of the tag, so it may contain commas.
+func parseBindTag(tag string) (name, def string, hasDefault bool) {
+	name, opts, _ := strings.Cut(tag, ",")
+	def, hasDefault = strings.CutPrefix(opts, "default=")
+	return name, def, hasDefault
+}
+
+// setField converts vals into field. Slices take every value, other kinds the
+// first. layout is the `time_format` tag, if any.
+func setField(field reflect.Value, vals []string, layout string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setValue(slice.Index(i), s, layout); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setValue(field, vals[0], layout)
+}
+
+// setValue converts a single string into v. time.Time is parsed with layout
+// (time.RFC3339 if empty), and types implementing encoding.TextUnmarshaler
+// (custom enums, net.IP, ...) convert themselves.
+func setValue(v reflect.Value, s, layout string) error {
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected the layout %q", s, layout)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("invalid value %q: %v", s, err)
+		}
+		return nil
+	}
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// DecodeNDJSON reads a newline-delimited JSON body (one value per line) and
+// calls fn once per record, so bulk uploads are processed as they stream in
+// rather than buffered. fn decodes the current record with decode:
+//
+//	err := c.DecodeNDJSON(func(decode func(interface{}) error) error {
+//		var e Event
+//		if err := decode(&e); err != nil {
+//			return err
+//		}
+//		return store.Insert(e)
+//	})
+//
+// Blank lines are skipped. A malformed record stops the stream with a 400
+// HTTPError naming its line, a line over MaxNDJSONLineSize or a body over
+// App.MaxBodySize with a 413, and an error returned by fn is returned as is.
+func (c *Context) DecodeNDJSON(fn func(decode func(interface{}) error) error) error {
+	if c.Request.Body == nil {
+		return nil
+	}
+	sc := bufio.NewScanner(c.Request.Body)
+	sc.Buffer(make([]byte, 0, 64<<10), MaxNDJSONLineSize)
+	line := 0
+	for sc.Scan() {
+		line++
+		data := sc.Bytes()
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		var decodeErr error
+		decode := func(v interface{}) error {
+			if err := json.Unmarshal(data, v); err != nil {
+				decodeErr = NewHTTPError(http.StatusBadRequest, fmt.Sprintf("line %d: invalid JSON: %v", line, err))
+				return decodeErr
+			}
+			return nil
+		}
+		if err := fn(decode); err != nil {
+			return err
+		}
+		if decodeErr != nil {
+			return decodeErr
+		}
+	}
+
+	err := sc.Err()
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bufio.ErrTooLong):
+		return NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("line %d: longer than %d bytes", line+1, MaxNDJSONLineSize))
+	default:
+		return bindError(err)
+	}
+}
+
+// bindError maps a decoding error to an HTTPError.
+func bindError(err error) HTTPError {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return NewHTTPError(http.StatusRequestEntityTooLarge)
+	case errors.Is(err, io.EOF):
+		return NewHTTPError(http.StatusBadRequest, "empty request body")
+	default:
+		return NewHTTPError(http.StatusBadRequest, "invalid JSON: "+err.Error())
+	}
+}
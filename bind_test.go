@@ -0,0 +1,313 @@
+package onion
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func bindRequest(body string) *Context {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	return New().newContext(httptest.NewRecorder(), req, nil)
+}
+
+// TestBindJSON ensures structs and slices bind, and bad JSON is a 400.
+func TestBindJSON(t *testing.T) {
+	var item bindItem
+	if err := bindRequest(`{"id":1,"name":"a"}`).BindJSON(&item); err != nil || item.ID != 1 {
+		t.Errorf("Expected item 1, got %+v (%v)", item, err)
+	}
+
+	var items []bindItem
+	if err := bindRequest(`[{"id":1},{"id":2}]`).BindJSON(&items); err != nil || len(items) != 2 {
+		t.Errorf("Expected 2 items, got %+v (%v)", items, err)
+	}
+
+	err := bindRequest(`{"id":`).BindJSON(&item)
+	if he, ok := err.(HTTPError); !ok || he.Code != http.StatusBadRequest {
+		t.Errorf("Expected a 400 HTTPError, got %v", err)
+	}
+}
+
+// TestBindJSONArray covers a normal batch, the element cap and a failing element.
+func TestBindJSONArray(t *testing.T) {
+	var items []bindItem
+	if err := bindRequest(`[{"id":1,"name":"a"},{"id":2,"name":"b"}]`).BindJSONArray(&items); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(items) != 2 || items[1].Name != "b" {
+		t.Errorf("Expected 2 items, got %+v", items)
+	}
+
+	c := bindRequest(`[{"id":1},{"id":2},{"id":3}]`)
+	c.app.MaxJSONArrayElements(2)
+	err := c.BindJSONArray(&items)
+	if he, ok := err.(HTTPError); !ok || he.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a 413 HTTPError, got %v", err)
+	}
+
+	err = bindRequest(`[{"id":1},{"id":"two"}]`).BindJSONArray(&items)
+	if err == nil || !strings.HasPrefix(err.Error(), "element 1:") {
+		t.Errorf("Expected the failing element to be named, got %v", err)
+	}
+
+	err = bindRequest(`{"id":1}`).BindJSONArray(&items)
+	if err == nil || err.Error() != "expected a JSON array" {
+		t.Errorf("Expected an array error, got %v", err)
+	}
+}
+
+// TestRequireFields ensures present fields pass and missing or null ones are listed.
+func TestRequireFields(t *testing.T) {
+	obj, err := bindRequest(`{"name":"a","email":"a@b.c","age":0}`).RequireFields("name", "email", "age")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if obj["name"] != "a" {
+		t.Errorf("Expected name 'a', got %v", obj["name"])
+	}
+
+	_, err = bindRequest(`{"name":"a","email":null}`).RequireFields("name", "email", "age")
+	he, ok := err.(HTTPError)
+	if !ok || he.Code != http.StatusBadRequest {
+		t.Fatalf("Expected a 400 HTTPError, got %v", err)
+	}
+	if he.Message != "missing required fields: email, age" {
+		t.Errorf("Expected the missing fields to be listed, got '%s'", he.Message)
+	}
+
+	if _, err := bindRequest(`[1,2]`).RequireFields("name"); err == nil {
+		t.Errorf("Expected an error for a non-object body")
+	}
+}
+
+// TestBindHeader ensures headers are converted into tagged fields.
+func TestBindHeader(t *testing.T) {
+	var h struct {
+		Version  string        `header:"X-Api-Version"`
+		PageSize int           `header:"x-page-size"`
+		Debug    bool          `header:"X-Debug"`
+		Wait     time.Duration `header:"X-Wait"`
+		Flags    []string      `header:"X-Flag"`
+		Other    string
+	}
+
+	c := bindRequest("")
+	c.Request.Header.Set("X-Api-Version", "2")
+	c.Request.Header.Set("X-Page-Size", "50")
+	c.Request.Header.Set("X-Wait", "2s")
+	c.Request.Header.Add("X-Flag", "a")
+	c.Request.Header.Add("X-Flag", "b")
+
+	if err := c.BindHeader(&h); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if h.Version != "2" || h.PageSize != 50 || h.Debug || h.Wait != 2*time.Second {
+		t.Errorf("Expected headers to be bound, got %+v", h)
+	}
+	if len(h.Flags) != 2 || h.Flags[1] != "b" {
+		t.Errorf("Expected repeated headers in the slice, got %v", h.Flags)
+	}
+
+	c.Request.Header.Set("X-Page-Size", "lots")
+	err := c.BindHeader(&h)
+	if he, ok := err.(HTTPError); !ok || he.Code != http.StatusBadRequest || !strings.Contains(he.Message, "x-page-size") {
+		t.Errorf("Expected a 400 naming the header, got %v", err)
+	}
+}
+
+// TestBindQuery ensures the primary name wins over aliases, aliases over the default.
+func TestBindQuery(t *testing.T) {
+	type search struct {
+		Term  string `query:"q,default=*" alias:"search,term"`
+		Limit int    `query:"limit,default=20"`
+	}
+
+	tests := map[string]search{
+		"/?q=go&search=rust&term=zig": {Term: "go", Limit: 20},
+		"/?search=rust&term=zig":      {Term: "rust", Limit: 20},
+		"/?term=zig&limit=5":          {Term: "zig", Limit: 5},
+		"/":                           {Term: "*", Limit: 20},
+		"/?q=":                        {Term: "", Limit: 20},
+	}
+	for target, want := range tests {
+		var got search
+		c := New().newContext(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil), nil)
+		if err := c.BindQuery(&got); err != nil {
+			t.Fatalf("%s: expected no error, got %v", target, err)
+		}
+		if got.Term != want.Term || got.Limit != want.Limit {
+			t.Errorf("%s: expected %+v, got %+v", target, want, got)
+		}
+	}
+
+	var got search
+	c := New().newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?limit=many", nil), nil)
+	err := c.BindQuery(&got)
+	if he, ok := err.(HTTPError); !ok || he.Code != http.StatusBadRequest || !strings.Contains(he.Message, "limit") {
+		t.Errorf("Expected a 400 naming the parameter, got %v", err)
+	}
+}
+
+// TestBindAll ensures the configured order decides which source wins a field.
+func TestBindAll(t *testing.T) {
+	type request struct {
+		ID    int    `param:"id"`
+		Name  string `json:"name" query:"name"`
+		Sort  string `query:"sort,default=title"`
+		Trace string `header:"X-Trace"`
+	}
+
+	bind := func(app *App) request {
+		var got request
+		app.handle("PUT", "/books/:id", func(c *Context) {
+			if err := c.BindAll(&got); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+		req := httptest.NewRequest("PUT", "/books/7?name=from-query", strings.NewReader(`{"name":"from-body"}`))
+		req.Header.Set("X-Trace", "abc")
+		app.mux.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	got := bind(New())
+	if got.ID != 7 || got.Name != "from-body" || got.Sort != "title" || got.Trace != "abc" {
+		t.Errorf("Expected the body to win by default, got %+v", got)
+	}
+
+	app := New()
+	app.BindOrder([]BindSource{BindQuery, BindBody, BindParams, BindHeader})
+	if got := bind(app); got.Name != "from-query" || got.ID != 7 {
+		t.Errorf("Expected the query to win, got %+v", got)
+	}
+}
+
+// bindStatus is a custom enum bound through encoding.TextUnmarshaler.
+type bindStatus int
+
+const (
+	statusOpen bindStatus = iota + 1
+	statusClosed
+)
+
+func (s *bindStatus) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "open":
+		*s = statusOpen
+	case "closed":
+		*s = statusClosed
+	default:
+		return fmt.Errorf("unknown status")
+	}
+	return nil
+}
+
+// TestBindTimeAndEnum ensures time_format layouts and TextUnmarshaler types convert.
+func TestBindTimeAndEnum(t *testing.T) {
+	var q struct {
+		Since    time.Time    `query:"since" time_format:"2006-01-02"`
+		Until    time.Time    `query:"until"`
+		Status   bindStatus   `query:"status"`
+		Statuses []bindStatus `query:"s"`
+	}
+
+	c := New().newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?since=2024-05-01&until=2024-06-01T10:00:00Z&status=closed&s=open&s=closed", nil), nil)
+	if err := c.BindQuery(&q); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !q.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || q.Until.Hour() != 10 {
+		t.Errorf("Expected the dates to be parsed, got %v and %v", q.Since, q.Until)
+	}
+	if q.Status != statusClosed || len(q.Statuses) != 2 || q.Statuses[0] != statusOpen {
+		t.Errorf("Expected the enums to be bound, got %v and %v", q.Status, q.Statuses)
+	}
+
+	c = New().newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?since=05/01/2024&status=pending", nil), nil)
+	err := c.BindQuery(&q)
+	if he, ok := err.(HTTPError); !ok || he.Code != http.StatusBadRequest || !strings.Contains(he.Message, "2006-01-02") {
+		t.Errorf("Expected a 400 naming the layout, got %v", err)
+	}
+}
+
+// TestDecodeNDJSON ensures records stream one by one and a bad line is reported by number.
+func TestDecodeNDJSON(t *testing.T) {
+	var items []bindItem
+	collect := func(decode func(interface{}) error) error {
+		var item bindItem
+		if err := decode(&item); err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	}
+
+	body := "{\"id\":1}\n{\"id\":2}\n\n{\"id\":3}"
+	if err := bindRequest(body).DecodeNDJSON(collect); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(items) != 3 || items[2].ID != 3 {
+		t.Errorf("Expected 3 records, got %+v", items)
+	}
+
+	items = nil
+	err := bindRequest("{\"id\":1}\n{\"id\":\n{\"id\":3}\n").DecodeNDJSON(collect)
+	he, ok := err.(HTTPError)
+	if !ok || he.Code != http.StatusBadRequest || !strings.HasPrefix(he.Message, "line 2:") {
+		t.Errorf("Expected a 400 for line 2, got %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("Expected decoding to stop at the bad line, got %d records", len(items))
+	}
+
+	items = nil
+	long := "{\"id\":1}\n{\"name\":\"" + strings.Repeat("a", MaxNDJSONLineSize) + "\"}\n"
+	err = bindRequest(long).DecodeNDJSON(collect)
+	if he, ok := err.(HTTPError); !ok || he.Code != http.StatusRequestEntityTooLarge || !strings.HasPrefix(he.Message, "line 2:") {
+		t.Errorf("Expected a 413 for the overlong line 2, got %v", err)
+	}
+}
+
+// TestBindJSONAndRespond ensures the result is rendered with the chosen status, and errors reach the error handler.
+func TestBindJSONAndRespond(t *testing.T) {
+	app := New()
+	app.handle("POST", "/items", func(c *Context) {
+		var item bindItem
+		c.BindJSONAndRespond(&item, func() (interface{}, error) {
+			if item.Name == "" {
+				return nil, NewHTTPError(http.StatusUnprocessableEntity, "name is required")
+			}
+			item.ID = 42
+			return item, nil
+		}, WithStatus(http.StatusCreated))
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("POST", "/items", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"name":"widget"}`)
+	if rec.Code != http.StatusCreated || strings.TrimSpace(rec.Body.String()) != `{"id":42,"name":"widget"}` {
+		t.Errorf("Expected 201 with the created item, got %d '%s'", rec.Code, rec.Body.String())
+	}
+
+	rec = post(`{"name":""}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "name is required") {
+		t.Errorf("Expected the process error as 422, got %d '%s'", rec.Code, rec.Body.String())
+	}
+
+	rec = post(`{bad`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code 400 for bad JSON, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,133 @@
+package onion
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------
+// Request body caching
+// ----------------------------------------------------
+
+// CacheBody reads the whole request body (honoring App.MaxBodySize), keeps a
+// copy on the Context and puts a fresh reader back on r.Body. After it runs,
+// middlewares can inspect c.RawBody() (e.g. to verify a webhook signature)
+// and the handler can still decode r.Body as usual.
+//
+// A body over the size limit is answered by the app's
+// RequestEntityTooLargeHandler (413 by default) and the chain is aborted.
+func CacheBody() HandlerFunc {
+	return func(c *Context) {
+		if c.rawBody != nil || c.Request.Body == nil {
+			return
+		}
+
+		data, err := io.ReadAll(c.Request.Body)
+		c.Request.Body.Close()
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				c.app.entityTooLarge(c)
+			} else {
+				c.String(http.StatusBadRequest, "Bad Request")
+			}
+			c.Abort()
+			return
+		}
+
+		c.rawBody = data
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	}
+}
+
+// RawBody returns the body cached by CacheBody, or nil if it hasn't run.
+func (c *Context) RawBody() []byte {
+	return c.rawBody
+}
+
+// ErrBodyNotUTF8 is returned by BodyString, along with the body as is, when
+// the body isn't valid UTF-8.
+var ErrBodyNotUTF8 = errors.New("onion: request body is not valid UTF-8")
+
+// BodyString reads the whole body as text, for text/plain and webhook
+// endpoints, and caches it like CacheBody so it can be read again (by
+// BodyString, RawBody, BodyReader or r.Body). Reads are capped at
+// App.MaxBodySize: a bigger body is a 413 HTTPError.
+//
+// A body that isn't valid UTF-8 is still returned, with ErrBodyNotUTF8, so
+// callers that accept any bytes can ignore that error.
+func (c *Context) BodyString() (string, error) {
+	if c.rawBody == nil {
+		if c.bodyConsumed {
+			return "", ErrBodyConsumed
+		}
+		data := []byte{}
+		if c.Request.Body != nil {
+			var err error
+			data, err = io.ReadAll(c.Request.Body)
+			c.Request.Body.Close()
+			if err != nil {
+				var maxErr *http.MaxBytesError
+				if errors.As(err, &maxErr) {
+					return "", NewHTTPError(http.StatusRequestEntityTooLarge)
+				}
+				return "", err
+			}
+		}
+		c.rawBody = data
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	}
+	if !utf8.Valid(c.rawBody) {
+		return string(c.rawBody), ErrBodyNotUTF8
+	}
+	return string(c.rawBody), nil
+}
+
+// ----------------------------------------------------
+// Streaming request bodies
+// ----------------------------------------------------
+
+// ErrBodyConsumed is returned when the request body is read a second time.
+var ErrBodyConsumed = errors.New("onion: request body already consumed")
+
+// BodyReader returns the request body for streaming, e.g. straight to disk
+// or object storage without holding it in memory. Reads are capped at
+// App.MaxBodySize and fail with *http.MaxBytesError past it.
+//
+// If CacheBody ran, the reader replays the cached copy, so this can be
+// called any number of times. Otherwise the body can only be streamed once:
+// later calls return a reader failing with ErrBodyConsumed.
+func (c *Context) BodyReader() io.Reader {
+	if c.rawBody != nil {
+		return bytes.NewReader(c.rawBody)
+	}
+	if c.bodyConsumed {
+		return errReader{ErrBodyConsumed}
+	}
+	c.bodyConsumed = true
+	if c.Request.Body == nil {
+		return http.NoBody
+	}
+	return c.Request.Body
+}
+
+// CopyBody streams the request body to dst and returns the number of bytes
+// copied. A body over App.MaxBodySize is a 413 HTTPError.
+func (c *Context) CopyBody(dst io.Writer) (int64, error) {
+	n, err := io.Copy(dst, c.BodyReader())
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return n, NewHTTPError(http.StatusRequestEntityTooLarge)
+	}
+	return n, err
+}
+
+// errReader fails every read with err.
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,115 @@
+package onion
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCacheBody ensures a middleware and the handler can both read the body.
+func TestCacheBody(t *testing.T) {
+	app := New()
+	app.Use(CacheBody())
+
+	var fromMiddleware string
+	app.Use(func(c *Context) {
+		fromMiddleware = string(c.RawBody())
+	})
+
+	app.handle("POST", "/hook", func(c *Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(data))
+	})
+
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(`{"id":1}`))
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if fromMiddleware != `{"id":1}` {
+		t.Errorf("Expected middleware to read the body, got '%s'", fromMiddleware)
+	}
+	if rec.Body.String() != `{"id":1}` {
+		t.Errorf("Expected handler to read the body again, got '%s'", rec.Body.String())
+	}
+}
+
+// TestCacheBodyTooLarge ensures an oversized body is rejected before the handler runs.
+func TestCacheBodyTooLarge(t *testing.T) {
+	app := New()
+	app.MaxBodySize(4)
+	app.Use(CacheBody())
+
+	called := false
+	app.handle("POST", "/hook", func(c *Context) {
+		called = true
+	})
+
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader("way too long"))
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code 413, got %d", rec.Code)
+	}
+	if called {
+		t.Errorf("Expected handler not to run after abort")
+	}
+}
+
+// TestCopyBody ensures the body streams once, or repeatedly once cached.
+func TestCopyBody(t *testing.T) {
+	app := New()
+	app.MaxBodySize(1 << 20)
+	payload := strings.Repeat("chunk", 1000)
+
+	var n int64
+	var errAgain error
+	app.handle("PUT", "/upload", func(c *Context) {
+		var buf bytes.Buffer
+		var err error
+		n, err = c.CopyBody(&buf)
+		if err != nil || buf.String() != payload {
+			t.Errorf("Expected the body to be copied, got %v", err)
+		}
+		_, errAgain = c.CopyBody(io.Discard)
+		c.NoContent()
+	})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/upload", strings.NewReader(payload)))
+	if n != int64(len(payload)) {
+		t.Errorf("Expected %d bytes copied, got %d", len(payload), n)
+	}
+	if !errors.Is(errAgain, ErrBodyConsumed) {
+		t.Errorf("Expected ErrBodyConsumed on the second copy, got %v", errAgain)
+	}
+
+	c, _ := NewTestContext("PUT", "/upload", strings.NewReader(payload))
+	CacheBody()(c)
+	for i := 0; i < 2; i++ {
+		if n, err := c.CopyBody(io.Discard); err != nil || n != int64(len(payload)) {
+			t.Errorf("Expected the cached body to be replayed, got %d bytes (%v)", n, err)
+		}
+	}
+}
+
+// TestBodyString ensures a text body can be read twice, and invalid UTF-8 is flagged.
+func TestBodyString(t *testing.T) {
+	c := bindRequest("hello, wörld")
+	for i := 0; i < 2; i++ {
+		if s, err := c.BodyString(); err != nil || s != "hello, wörld" {
+			t.Errorf("Read %d: expected 'hello, wörld', got '%s' (%v)", i+1, s, err)
+		}
+	}
+	if data, _ := io.ReadAll(c.Request.Body); string(data) != "hello, wörld" {
+		t.Errorf("Expected r.Body to be readable after BodyString, got '%s'", data)
+	}
+
+	s, err := bindRequest("caf\xe9").BodyString()
+	if !errors.Is(err, ErrBodyNotUTF8) || s != "caf\xe9" {
+		t.Errorf("Expected the raw body with ErrBodyNotUTF8, got %q (%v)", s, err)
+	}
+}
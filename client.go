@@ -0,0 +1,68 @@
+package onion
+
+import (
+	"context"
+	"net/http"
+)
+
+// ----------------------------------------------------
+// Outgoing HTTP calls
+// ----------------------------------------------------
+
+// SetHTTPClient sets the client Context.HTTPClient builds on: its transport,
+// timeout, redirect policy and cookie jar are kept. nil restores the default
+// (a plain &http.Client{} over http.DefaultTransport).
+func (a *App) SetHTTPClient(client *http.Client) {
+	a.httpClient = client
+}
+
+// HTTPClient returns a client for upstream calls made on behalf of this
+// request. Every request it sends is tied to c.Request.Context(): when the
+// client disconnects, or a Timeout/MethodTimeouts deadline passes, the
+// upstream call is cancelled too instead of running on for nobody.
+//
+//	req, _ := http.NewRequest("GET", "http://inventory/items/"+c.Param("id"), nil)
+//	resp, err := c.HTTPClient().Do(req)
+//
+// A request that already carries its own context keeps it, and is cancelled
+// by whichever ends first. Behind the Budget middleware, requests also carry
+// the remaining budget in DeadlineHeader.
+func (c *Context) HTTPClient() *http.Client {
+	client := &http.Client{}
+	if c.app != nil && c.app.httpClient != nil {
+		*client = *c.app.httpClient
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &contextTransport{base: base, ctx: c.Request.Context(), budget: c.budget}
+	return client
+}
+
+// contextTransport ties outgoing requests to the incoming request's context.
+type contextTransport struct {
+	base   http.RoundTripper
+	ctx    context.Context
+	budget bool // forward the deadline, see Budget
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if deadline, ok := t.ctx.Deadline(); ok && t.budget {
+		// RoundTrippers must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Header.Set(DeadlineHeader, formatDeadline(deadline))
+	}
+	if req.Context() == context.Background() {
+		return t.base.RoundTrip(req.WithContext(t.ctx))
+	}
+
+	// Keep the caller's context and also cancel when the incoming request
+	// ends. The AfterFunc is released when t.ctx is done, at the latest when
+	// the incoming request finishes.
+	ctx, cancel := context.WithCancelCause(req.Context())
+	context.AfterFunc(t.ctx, func() {
+		cancel(context.Cause(t.ctx))
+	})
+	return t.base.RoundTrip(req.WithContext(ctx))
+}
@@ -0,0 +1,55 @@
+package onion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestHTTPClientCancellation ensures an upstream call is cancelled along with the incoming request.
+func TestHTTPClientCancellation(t *testing.T) {
+	upstreamStarted := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(upstreamStarted)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer upstream.Close()
+
+	app := New()
+	app.SetHTTPClient(&http.Client{Timeout: 10 * time.Second})
+	upstreamErr := make(chan error, 1)
+	app.handle("GET", "/proxy", func(c *Context) {
+		req, _ := http.NewRequest("GET", upstream.URL, nil)
+		resp, err := c.HTTPClient().Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		upstreamErr <- err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest("GET", "/proxy", nil).WithContext(ctx)
+	go app.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	<-upstreamStarted
+	start := time.Now()
+	cancel()
+
+	select {
+	case err := <-upstreamErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the upstream call to be cancelled, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected a prompt cancellation, took %v", time.Since(start))
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected the upstream call to end when the request was cancelled")
+	}
+}
@@ -0,0 +1,220 @@
+package onion
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------
+// Conditional requests
+// ----------------------------------------------------
+
+// SetLastModified sets the Last-Modified header. Zero times are ignored.
+func (c *Context) SetLastModified(modtime time.Time) {
+	if modtime.IsZero() || modtime.Equal(time.Unix(0, 0)) {
+		return
+	}
+	c.Response.Header().Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+}
+
+// NotModifiedSince sets Last-Modified to modtime and checks the request's
+// If-Modified-Since header. If the resource hasn't changed since, it sends
+// 304 Not Modified and returns true, so the handler can stop there:
+//
+//	if c.NotModifiedSince(book.UpdatedAt) {
+//		return
+//	}
+//	c.JSON(http.StatusOK, book)
+//
+// Only GET and HEAD requests are checked. HTTP dates have second precision,
+// so modtime is compared truncated to the second.
+func (c *Context) NotModifiedSince(modtime time.Time) bool {
+	c.SetLastModified(modtime)
+
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	ims := c.Request.Header.Get("If-Modified-Since")
+	if ims == "" || modtime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil || modtime.Truncate(time.Second).After(t) {
+		return false
+	}
+
+	h := c.Response.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	c.Response.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// Preconditions holds the parsed conditional request headers (RFC 7232).
+// Entity tags are kept as sent, quotes and W/ prefix included; invalid or
+// missing dates are zero.
+type Preconditions struct {
+	IfMatch           []string
+	IfNoneMatch       []string
+	IfModifiedSince   time.Time
+	IfUnmodifiedSince time.Time
+
+	method string
+}
+
+// Preconditions parses the request's conditional headers, for handlers that
+// check them against their own resource, e.g. for optimistic concurrency:
+//
+//	if status, ok := c.Preconditions().Check(book.ETag, book.UpdatedAt); !ok {
+//		c.Response.WriteHeader(status)
+//		return
+//	}
+//	// update the book
+func (c *Context) Preconditions() Preconditions {
+	h := c.Request.Header
+	p := Preconditions{
+		IfMatch:     parseETags(h.Get("If-Match")),
+		IfNoneMatch: parseETags(h.Get("If-None-Match")),
+		method:      c.Request.Method,
+	}
+	if t, err := http.ParseTime(h.Get("If-Modified-Since")); err == nil {
+		p.IfModifiedSince = t
+	}
+	if t, err := http.ParseTime(h.Get("If-Unmodified-Since")); err == nil {
+		p.IfUnmodifiedSince = t
+	}
+	return p
+}
+
+// Check evaluates the preconditions against the resource's current entity
+// tag and modification time, in the order of RFC 7232 section 6:
+//
+//  1. If-Match, else If-Unmodified-Since: 412 Precondition Failed if the
+//     resource changed.
+//  2. If-None-Match, else If-Modified-Since (GET and HEAD only): 304 Not
+//     Modified for GET and HEAD if it didn't, 412 for If-None-Match on other
+//     methods.
+//
+// It returns 200 and true if the request should go on. An empty etag means
+// the resource doesn't exist (so "If-Match: *" fails), and a zero modtime
+// skips the date checks. etag may be given with or without quotes.
+func (p Preconditions) Check(etag string, modtime time.Time) (status int, ok bool) {
+	if etag != "" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = strconv.Quote(etag)
+	}
+	modtime = modtime.Truncate(time.Second)
+	safe := p.method == http.MethodGet || p.method == http.MethodHead
+
+	if len(p.IfMatch) > 0 {
+		if !etagMatch(p.IfMatch, etag, true) {
+			return http.StatusPreconditionFailed, false
+		}
+	} else if !p.IfUnmodifiedSince.IsZero() && !modtime.IsZero() && modtime.After(p.IfUnmodifiedSince) {
+		return http.StatusPreconditionFailed, false
+	}
+
+	if len(p.IfNoneMatch) > 0 {
+		if etagMatch(p.IfNoneMatch, etag, false) {
+			if safe {
+				return http.StatusNotModified, false
+			}
+			return http.StatusPreconditionFailed, false
+		}
+	} else if safe && !p.IfModifiedSince.IsZero() && !modtime.IsZero() && !modtime.After(p.IfModifiedSince) {
+		return http.StatusNotModified, false
+	}
+	return http.StatusOK, true
+}
+
+// etagMatch reports whether etag matches one of tags, "*" matching any
+// existing resource. The strong comparison never matches weak tags.
+func etagMatch(tags []string, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, tag := range tags {
+		if tag == "*" {
+			return true
+		}
+		if strong {
+			if tag == etag && !strings.HasPrefix(tag, "W/") {
+				return true
+			}
+		} else if strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseETags splits an If-Match or If-None-Match value into entity tags.
+// Tags are quoted and may contain commas, so the list is scanned rather
+// than split.
+func parseETags(value string) []string {
+	var tags []string
+	for {
+		value = strings.TrimLeft(value, " \t,")
+		if value == "" {
+			return tags
+		}
+		if value[0] == '*' {
+			tags = append(tags, "*")
+			value = value[1:]
+			continue
+		}
+		start := 0
+		if strings.HasPrefix(value, "W/") {
+			start = 2
+		}
+		if len(value) <= start || value[start] != '"' {
+			return tags // malformed, keep what was parsed
+		}
+		end := strings.IndexByte(value[start+1:], '"')
+		if end < 0 {
+			return tags
+		}
+		end += start + 2
+		tags = append(tags, value[:end])
+		value = value[end:]
+	}
+}
+
+// ----------------------------------------------------
+// Cache-Control
+// ----------------------------------------------------
+
+// CacheControl sets the Cache-Control header to the given directives, e.g.
+// c.CacheControl("public", "max-age=600", "immutable"). With no directives
+// the header is removed.
+func (c *Context) CacheControl(directives ...string) {
+	if len(directives) == 0 {
+		c.Response.Header().Del("Cache-Control")
+		return
+	}
+	c.Response.Header().Set("Cache-Control", strings.Join(directives, ", "))
+}
+
+// NoCache keeps browsers and proxies from storing the response at all,
+// for pages that must always be fresh.
+func (c *Context) NoCache() {
+	c.CacheControl("no-store", "no-cache", "must-revalidate")
+}
+
+// CachePublic lets browsers and shared caches (CDNs, proxies) keep the
+// response for maxAge, rounded down to the second.
+func (c *Context) CachePublic(maxAge time.Duration) {
+	c.CacheControl("public", maxAgeDirective(maxAge))
+}
+
+// CachePrivate lets only the user's browser keep the response for maxAge,
+// for responses specific to the user.
+func (c *Context) CachePrivate(maxAge time.Duration) {
+	c.CacheControl("private", maxAgeDirective(maxAge))
+}
+
+// maxAgeDirective renders "max-age=<seconds>", never negative.
+func maxAgeDirective(d time.Duration) string {
+	return "max-age=" + strconv.FormatInt(int64(max(d, 0)/time.Second), 10)
+}
@@ -0,0 +1,118 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestNotModifiedSince ensures older If-Modified-Since values get the body and newer ones a 304.
+func TestNotModifiedSince(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+
+	app := New()
+	app.handle("GET", "/books/1", func(c *Context) {
+		if c.NotModifiedSince(updated) {
+			return
+		}
+		c.String(http.StatusOK, "book")
+	})
+
+	tests := []struct {
+		ims  string
+		code int
+		body string
+	}{
+		{"", http.StatusOK, "book"},
+		{updated.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK, "book"},
+		{updated.Format(http.TimeFormat), http.StatusNotModified, ""},
+		{updated.Add(time.Hour).Format(http.TimeFormat), http.StatusNotModified, ""},
+		{"yesterday", http.StatusOK, "book"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/books/1", nil)
+		if tt.ims != "" {
+			req.Header.Set("If-Modified-Since", tt.ims)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code || rec.Body.String() != tt.body {
+			t.Errorf("If-Modified-Since %q: expected %d '%s', got %d '%s'", tt.ims, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Last-Modified") != "Wed, 01 May 2024 12:00:00 GMT" {
+			t.Errorf("Expected Last-Modified to be set, got '%s'", rec.Header().Get("Last-Modified"))
+		}
+	}
+}
+
+// TestCacheControl ensures each helper sets the expected header.
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(c *Context)
+		want string
+	}{
+		{"directives", func(c *Context) { c.CacheControl("public", "max-age=60", "immutable") }, "public, max-age=60, immutable"},
+		{"no cache", func(c *Context) { c.NoCache() }, "no-store, no-cache, must-revalidate"},
+		{"public", func(c *Context) { c.CachePublic(time.Hour) }, "public, max-age=3600"},
+		{"private", func(c *Context) { c.CachePrivate(90 * time.Second) }, "private, max-age=90"},
+		{"negative", func(c *Context) { c.CachePrivate(-time.Second) }, "private, max-age=0"},
+		{"removed", func(c *Context) { c.NoCache(); c.CacheControl() }, ""},
+	}
+	for _, tt := range tests {
+		c := New().newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), nil)
+		tt.set(c)
+		if got := c.Response.Header().Get("Cache-Control"); got != tt.want {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.want, got)
+		}
+	}
+}
+
+// TestPreconditions ensures the conditional headers are evaluated in RFC 7232 order.
+func TestPreconditions(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 500, time.UTC)
+	before := updated.Add(-time.Hour).Format(http.TimeFormat)
+	after := updated.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		etag    string
+		status  int
+	}{
+		{"none", "PUT", nil, `"v2"`, http.StatusOK},
+		{"if-match hit", "PUT", map[string]string{"If-Match": `"v1", "v2"`}, `"v2"`, http.StatusOK},
+		{"if-match miss", "PUT", map[string]string{"If-Match": `"v1"`}, `"v2"`, http.StatusPreconditionFailed},
+		{"if-match weak", "PUT", map[string]string{"If-Match": `W/"v2"`}, `"v2"`, http.StatusPreconditionFailed},
+		{"if-match star", "PUT", map[string]string{"If-Match": "*"}, `"v2"`, http.StatusOK},
+		{"if-match star missing", "PUT", map[string]string{"If-Match": "*"}, "", http.StatusPreconditionFailed},
+		{"unquoted etag", "PUT", map[string]string{"If-Match": `"v2"`}, "v2", http.StatusOK},
+		{"if-unmodified-since ok", "PUT", map[string]string{"If-Unmodified-Since": after}, `"v2"`, http.StatusOK},
+		{"if-unmodified-since changed", "PUT", map[string]string{"If-Unmodified-Since": before}, `"v2"`, http.StatusPreconditionFailed},
+		{"if-match wins over if-unmodified-since", "PUT", map[string]string{"If-Match": `"v2"`, "If-Unmodified-Since": before}, `"v2"`, http.StatusOK},
+		{"if-none-match get", "GET", map[string]string{"If-None-Match": `W/"v2"`}, `"v2"`, http.StatusNotModified},
+		{"if-none-match put", "PUT", map[string]string{"If-None-Match": `"v2"`}, `"v2"`, http.StatusPreconditionFailed},
+		{"if-none-match star create", "PUT", map[string]string{"If-None-Match": "*"}, "", http.StatusOK},
+		{"if-none-match miss", "GET", map[string]string{"If-None-Match": `"v1"`}, `"v2"`, http.StatusOK},
+		{"if-modified-since unchanged", "GET", map[string]string{"If-Modified-Since": after}, `"v2"`, http.StatusNotModified},
+		{"if-modified-since changed", "GET", map[string]string{"If-Modified-Since": before}, `"v2"`, http.StatusOK},
+		{"if-modified-since ignored on put", "PUT", map[string]string{"If-Modified-Since": after}, `"v2"`, http.StatusOK},
+		{"if-none-match wins over if-modified-since", "GET", map[string]string{"If-None-Match": `"v1"`, "If-Modified-Since": after}, `"v2"`, http.StatusOK},
+		{"if-match checked before if-none-match", "GET", map[string]string{"If-Match": `"v1"`, "If-None-Match": `"v2"`}, `"v2"`, http.StatusPreconditionFailed},
+		{"invalid date ignored", "PUT", map[string]string{"If-Unmodified-Since": "yesterday"}, `"v2"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/books/1", nil)
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+		c := &Context{Request: req}
+		status, ok := c.Preconditions().Check(tt.etag, updated)
+		if status != tt.status || ok != (tt.status == http.StatusOK) {
+			t.Errorf("%s: Expected status %d, got %d (ok=%v)", tt.name, tt.status, status, ok)
+		}
+	}
+}
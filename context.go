@@ -0,0 +1,334 @@
+package onion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HandlerFunc defines the function signature for route handlers.
+type HandlerFunc func(*Context)
+
+var _ context.Context = (*Context)(nil)
+
+// abortIndex is large enough that Next() never runs anything after an Abort().
+const abortIndex = math.MaxInt / 2
+
+// Context wraps http.ResponseWriter and *http.Request, plus path parameters.
+// It also implements context.Context by delegating to the request's context.
+type Context struct {
+	Response http.ResponseWriter
+	Request  *http.Request
+	params   []Param
+
+	app       *App
+	writer    *responseWriter
+	route     routeKey // the matched route, zero if none
+	routeMeta map[string]interface{}
+
+	forwards int // see Forward
+
+	// Middleware chain: handlers[index] is the one currently running
+	handlers []HandlerFunc
+	index    int
+
+	rawBody      []byte
+	bodyConsumed bool // set by BodyReader
+	store        map[string]interface{}
+	errors       []error
+	meta         map[string]interface{} // see SetMeta
+	flashes      map[string][]string    // see Flash
+	budget       bool                   // see Budget
+
+	fields    map[string]interface{}
+	requestID string
+	traceID   string
+}
+
+// Next runs the remaining middlewares and the handler, then returns. Calling it
+// is optional: a middleware that doesn't call Next is simply followed by the
+// next one once it returns. Calling it lets a middleware do work after the handler.
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Chain bundles several middlewares into one, e.g.
+//
+//	app.Use(onion.Chain(onion.RequestID(), onion.Logger(onion.LoggerConfig{})))
+//
+// The bundle behaves exactly as if each middleware had been registered on its
+// own, in order: Next and Abort work across it.
+func Chain(mws ...HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		// Splice the bundle into the running chain, right after ourselves
+		handlers := make([]HandlerFunc, 0, len(c.handlers)+len(mws))
+		handlers = append(handlers, c.handlers[:c.index+1]...)
+		handlers = append(handlers, mws...)
+		handlers = append(handlers, c.handlers[c.index+1:]...)
+		c.handlers = handlers
+	}
+}
+
+// Abort stops the chain: no further middlewares or the handler will run.
+// It doesn't write anything, so respond (e.g. with c.String) before aborting.
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether Abort was called.
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// ResponseWritten reports whether the response status has been sent, after
+// which headers can't change and an error page can't replace the response.
+// Middlewares that buffer the response (Timeout, response transformers)
+// only send it once they're done, so behind them this stays false while
+// the handler runs.
+func (c *Context) ResponseWritten() bool {
+	return c.writer.written
+}
+
+// ResponseStatus returns the status sent, or 0 if none was sent yet.
+func (c *Context) ResponseStatus() int {
+	if !c.writer.written {
+		return 0
+	}
+	return c.writer.status
+}
+
+// AbortConnection closes the client connection without sending a response
+// and aborts the chain, a cheap way to drop an obviously malicious client.
+// Middlewares that run after c.Next (Logger, stats) still see the request,
+// with status 0 and no bytes written. It returns an error, leaving the
+// request untouched, if the connection can't be hijacked (HTTP/2, test
+// recorders) or a response was already started.
+func (c *Context) AbortConnection() error {
+	if c.writer.written {
+		return errors.New("onion: can't abort the connection after the response started")
+	}
+	conn, _, err := http.NewResponseController(c.writer.ResponseWriter).Hijack()
+	if err != nil {
+		return err
+	}
+	c.writer.status = 0
+	c.writer.written = true
+	c.Abort()
+	return conn.Close()
+}
+
+// Deadline returns the request context's deadline, if any.
+func (c *Context) Deadline() (time.Time, bool) {
+	return c.Request.Context().Deadline()
+}
+
+// Done is closed when the request is cancelled: the client went away, the
+// server is shutting down, or a timeout middleware's deadline passed.
+func (c *Context) Done() <-chan struct{} {
+	return c.Request.Context().Done()
+}
+
+// Err explains why Done was closed (context.Canceled or
+// context.DeadlineExceeded), or returns nil while the request is alive.
+// Not to be confused with Errors, which lists errors passed to c.Error.
+func (c *Context) Err() error {
+	return c.Request.Context().Err()
+}
+
+// Value makes *Context a context.Context, so c can be passed straight to
+// libraries that take one. Lookup order:
+//
+//  1. if key is a string set with c.Set, that value is returned;
+//  2. otherwise the lookup falls through to c.Request.Context().Value(key).
+//
+// Because c.Set keys are plain strings, they can shadow string keys used by
+// other packages on the request context; use unexported key types there.
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if v, ok := c.store[k]; ok {
+			return v
+		}
+	}
+	return c.Request.Context().Value(key)
+}
+
+// ClientIP returns the IP of the client: RemoteAddr without the port, or,
+// for requests from a trusted proxy (see App.TrustedProxies), the address
+// the proxies recorded in X-Forwarded-For. That's the rightmost entry that
+// isn't itself a trusted proxy, since the entries left of it were sent by
+// the client and can be forged.
+func (c *Context) ClientIP() string {
+	if c.app != nil {
+		return c.app.clientIP(c.Request)
+	}
+	return remoteIP(c.Request)
+}
+
+// Set stores a value for the lifetime of the request, e.g. the user loaded by
+// an auth middleware.
+func (c *Context) Set(key string, value interface{}) {
+	if c.store == nil {
+		c.store = make(map[string]interface{})
+	}
+	c.store[key] = value
+}
+
+// Get returns a value stored with Set.
+func (c *Context) Get(key string) (interface{}, bool) {
+	v, ok := c.store[key]
+	return v, ok
+}
+
+// WithField attaches a structured field (user ID, tenant, ...) to the request.
+// The Logger middleware includes every field in its log line. It returns c so
+// calls can be chained.
+func (c *Context) WithField(key string, value interface{}) *Context {
+	if c.fields == nil {
+		c.fields = make(map[string]interface{})
+	}
+	c.fields[key] = value
+	return c
+}
+
+// Fields returns a copy of the fields attached with WithField.
+func (c *Context) Fields() map[string]interface{} {
+	out := make(map[string]interface{}, len(c.fields))
+	for k, v := range c.fields {
+		out[k] = v
+	}
+	return out
+}
+
+// String is a helper for sending plain text.
+func (c *Context) String(statusCode int, msg string) {
+	c.Response.WriteHeader(statusCode)
+	c.Response.Write([]byte(msg))
+}
+
+// NoContent sends 204 No Content, the usual answer to a successful DELETE or
+// PUT. Content-Type and Content-Length are dropped, and later body writes fail
+// with http.ErrBodyNotAllowed instead of reaching the client.
+func (c *Context) NoContent() {
+	h := c.Response.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	c.Response.WriteHeader(http.StatusNoContent)
+}
+
+// JSON is a helper for sending JSON data.
+func (c *Context) JSON(statusCode int, data interface{}) {
+	c.Response.Header().Set("Content-Type", "application/json")
+	c.Response.WriteHeader(statusCode)
+	json.NewEncoder(c.Response).Encode(data)
+}
+
+// Flush sends any buffered data to the client right away, if the underlying
+// writer supports it. Once flushed, the status and headers are committed and
+// can no longer be changed. It is safe to call Flush repeatedly.
+func (c *Context) Flush() {
+	if f, ok := c.Response.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Route describes the route that matched this request: method, pattern,
+// host, version and Meta. Handler is left nil. The zero Route means no
+// route matched (e.g. in the 404 handler).
+func (c *Context) Route() Route {
+	return Route{
+		Method:        c.route.method,
+		Pattern:       c.route.pattern,
+		Host:          c.route.host,
+		AcceptVersion: c.route.version,
+		Meta:          c.routeMeta,
+	}
+}
+
+// RouteContextKey and ParamsContextKey are the request context keys under
+// which the matched Route and []Param are stored, for code that only sees
+// the *http.Request. See RouteFromContext and ParamsFromContext.
+var (
+	RouteContextKey  = &contextKey{"route"}
+	ParamsContextKey = &contextKey{"params"}
+)
+
+// contextKey is the type of the request context keys, so they can't collide.
+type contextKey struct{ name string }
+
+func (k *contextKey) String() string {
+	return "onion context key " + k.name
+}
+
+// RouteFromContext returns the route matched for the request ctx belongs
+// to, for libraries and net/http middleware that only have r.Context().
+// ok is false outside a matched route.
+func RouteFromContext(ctx context.Context) (route Route, ok bool) {
+	route, ok = ctx.Value(RouteContextKey).(Route)
+	return route, ok
+}
+
+// ParamsFromContext returns the path params of the request ctx belongs to,
+// in pattern order, or nil.
+func ParamsFromContext(ctx context.Context) []Param {
+	params, _ := ctx.Value(ParamsContextKey).([]Param)
+	return params
+}
+
+// setRoute records the matched route on c and in the request context.
+func (c *Context) setRoute(key routeKey, entry *routeEntry, params []Param) {
+	c.params = params
+	c.route, c.routeMeta = key, entry.meta
+	ctx := context.WithValue(c.Request.Context(), RouteContextKey, c.Route())
+	ctx = context.WithValue(ctx, ParamsContextKey, params)
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// Param is a path parameter matched from the URL.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Param fetches a path param like ":bookId".
+func (c *Context) Param(key string) string {
+	// Walk backwards so a repeated name resolves to its last occurrence
+	for i := len(c.params) - 1; i >= 0; i-- {
+		if c.params[i].Key == key {
+			return c.params[i].Value
+		}
+	}
+	return ""
+}
+
+// Params returns the path params in the order they appear in the route
+// pattern: "/a/:x/b/:y" gives x, then y. The slice must not be modified.
+func (c *Context) Params() []Param {
+	return c.params
+}
+
+// Wildcard returns what the route's trailing "*name" segment captured, the
+// same value as c.Param("name"). It is "" if the route has no wildcard.
+//
+// The remainder never starts with a slash: "/static/*path" gives "a/b" for
+// "/static/a/b", "index.html" for "/static/index.html", and "" for both
+// "/static/" and "/static". With App.WildcardLeadingSlash(true), Wildcard
+// (not Param) returns "/a/b", "/index.html" and "/" instead.
+func (c *Context) Wildcard() string {
+	i := strings.LastIndexByte(c.route.pattern, '/')
+	if i < 0 || !strings.HasPrefix(c.route.pattern[i+1:], "*") {
+		return ""
+	}
+	rest := c.Param(c.route.pattern[i+2:])
+	if c.app.wildcardSlash {
+		return "/" + rest
+	}
+	return rest
+}
@@ -0,0 +1,274 @@
+package onion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestContextCancellation ensures a cancelled request surfaces through Done and Err.
+func TestContextCancellation(t *testing.T) {
+	app := New()
+
+	var err error
+	var done bool
+	app.handle("GET", "/", func(c *Context) {
+		select {
+		case <-c.Done():
+			done = true
+		case <-time.After(time.Second):
+		}
+		err = c.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	app.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !done {
+		t.Errorf("Expected Done to be closed for a cancelled request")
+	}
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestContextDeadline ensures a request deadline is visible through Deadline.
+func TestContextDeadline(t *testing.T) {
+	app := New()
+	app.Use(HeaderTimeout("X-Request-Timeout", time.Minute))
+
+	var ok bool
+	var err error
+	app.handle("GET", "/", func(c *Context) {
+		_, ok = c.Deadline()
+		err = c.Err()
+	})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !ok {
+		t.Errorf("Expected a deadline")
+	}
+	if err != nil {
+		t.Errorf("Expected no error on a live request, got %v", err)
+	}
+}
+
+type ctxKey struct{}
+
+// lookup stands in for a library that only knows about context.Context.
+func lookup(ctx context.Context, key interface{}) (interface{}, error) {
+	return ctx.Value(key), ctx.Err()
+}
+
+// TestContextAsContext ensures c can be passed as a context.Context, with Set values first.
+func TestContextAsContext(t *testing.T) {
+	app := New()
+
+	var fromStore, fromRequest, shadowed interface{}
+	var err error
+	app.handle("GET", "/", func(c *Context) {
+		c.Set("user", "alice")
+		c.Set("trace", "from-store")
+		fromStore, _ = lookup(c, "user")
+		fromRequest, _ = lookup(c, ctxKey{})
+		shadowed, err = lookup(c, "trace")
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "from-request")
+	ctx = context.WithValue(ctx, "trace", "from-request")
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	app.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	if fromStore != "alice" {
+		t.Errorf("Expected 'alice' from the store, got %v", fromStore)
+	}
+	if fromRequest != "from-request" {
+		t.Errorf("Expected 'from-request' from the request context, got %v", fromRequest)
+	}
+	if shadowed != "from-store" {
+		t.Errorf("Expected the store to win over the request context, got %v", shadowed)
+	}
+	if err != context.Canceled {
+		t.Errorf("Expected cancellation to be observed, got %v", err)
+	}
+}
+
+// TestChain ensures composed middlewares run in order, around the handler, and can abort.
+func TestChain(t *testing.T) {
+	var order []string
+	step := func(name string) HandlerFunc {
+		return func(c *Context) {
+			order = append(order, name)
+		}
+	}
+	around := func(c *Context) {
+		order = append(order, "around:before")
+		c.Next()
+		order = append(order, "around:after")
+	}
+
+	app := New()
+	app.Use(Chain(step("a"), around, step("b")))
+	app.Use(step("c"))
+	app.handle("GET", "/", func(c *Context) {
+		order = append(order, "handler")
+	})
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	want := "a,around:before,b,c,handler,around:after"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("Expected order '%s', got '%s'", want, got)
+	}
+
+	order = nil
+	app = New()
+	app.Use(Chain(step("a"), func(c *Context) {
+		order = append(order, "stop")
+		c.Abort()
+	}, step("b")))
+	app.Use(step("c"))
+	app.handle("GET", "/", func(c *Context) {
+		order = append(order, "handler")
+	})
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if got := strings.Join(order, ","); got != "a,stop" {
+		t.Errorf("Expected abort to stop the rest, got '%s'", got)
+	}
+}
+
+// TestParamsOrder ensures Params keeps the order of the route pattern.
+func TestParamsOrder(t *testing.T) {
+	app := New()
+
+	var got []Param
+	handler := func(c *Context) {
+		got = c.Params()
+	}
+	app.handle("GET", "/a/:x/b/:y", handler)
+	app.handle("GET", "/files/:dir/:name.:ext", handler)
+
+	tests := []struct {
+		path string
+		want []Param
+	}{
+		{"/a/1/b/2", []Param{{"x", "1"}, {"y", "2"}}},
+		{"/files/docs/report.tar.gz", []Param{{"dir", "docs"}, {"name", "report.tar"}, {"ext", "gz"}}},
+	}
+
+	for _, tt := range tests {
+		got = nil
+		app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected params %v, got %v", tt.path, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected params %v, got %v", tt.path, tt.want, got)
+				break
+			}
+		}
+	}
+}
+
+// TestNoContent ensures a 204 goes out without a body or content headers.
+func TestNoContent(t *testing.T) {
+	app := New()
+	app.handle("DELETE", "/books/:id", func(c *Context) {
+		c.Response.Header().Set("Content-Type", "application/json")
+		c.NoContent()
+		c.String(http.StatusOK, "oops")
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/books/1", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code 204, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected an empty body, got '%s'", rec.Body.String())
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != "" && cl != "0" {
+		t.Errorf("Expected no Content-Length, got '%s'", cl)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "" {
+		t.Errorf("Expected no Content-Type, got '%s'", ct)
+	}
+}
+
+// TestAbortConnection ensures the client sees a closed connection and the Logger still runs.
+func TestAbortConnection(t *testing.T) {
+	logs := &captureLogger{}
+	logged := make(chan struct{}, 1)
+	app := New()
+	app.SetLogger(logs)
+	app.Use(func(c *Context) {
+		c.Next()
+		logged <- struct{}{}
+	})
+	app.Use(Logger(LoggerConfig{}))
+	app.handle("GET", "/probe", func(c *Context) {
+		if err := c.AbortConnection(); err != nil {
+			t.Errorf("Expected the connection to be hijacked, got %v", err)
+		}
+	})
+
+	srv := httptest.NewServer(app.mux)
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL + "/probe"); err == nil {
+		resp.Body.Close()
+		t.Errorf("Expected the connection to be closed, got status code %d", resp.StatusCode)
+	}
+	<-logged
+	if !strings.HasPrefix(logs.String(), "GET /probe 0 0B") {
+		t.Errorf("Expected the aborted request to be logged, got '%s'", logs.String())
+	}
+
+	// A recorder can't be hijacked
+	var err error
+	app.handle("GET", "/recorded", func(c *Context) {
+		err = c.AbortConnection()
+	})
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/recorded", nil))
+	if err == nil {
+		t.Errorf("Expected an error without hijacking support")
+	}
+}
+
+// TestRouteFromContext ensures code holding only the *http.Request sees the route and params.
+func TestRouteFromContext(t *testing.T) {
+	var route Route
+	var params []Param
+	var ok bool
+	plain := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok = RouteFromContext(r.Context())
+		params = ParamsFromContext(r.Context())
+	})
+
+	app := New()
+	app.handle("GET", "/books/:id/pages/:page", func(c *Context) {
+		plain.ServeHTTP(c.Response, c.Request)
+	}, WithMeta("owner", "catalog"))
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/books/7/pages/3", nil))
+
+	if !ok || route.Method != "GET" || route.Pattern != "/books/:id/pages/:page" || route.Meta["owner"] != "catalog" {
+		t.Errorf("Expected the matched route, got %+v (%v)", route, ok)
+	}
+	if len(params) != 2 || params[0] != (Param{"id", "7"}) || params[1] != (Param{"page", "3"}) {
+		t.Errorf("Expected id and page params, got %v", params)
+	}
+
+	if _, ok := RouteFromContext(context.Background()); ok {
+		t.Errorf("Expected no route outside a request")
+	}
+}
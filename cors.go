@@ -0,0 +1,156 @@
+package onion
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------
+// CORS
+// ----------------------------------------------------
+
+// CORSConfig configures App.CORS.
+type CORSConfig struct {
+	// AllowOrigins lists the origins allowed to call the API, e.g.
+	// "https://app.example.com". "*" allows any origin.
+	AllowOrigins []string
+
+	// AllowMethods limits the methods advertised to preflights. Empty means
+	// whatever the route table accepts for the path.
+	AllowMethods []string
+
+	// AllowHeaders lists the request headers clients may send. Empty means
+	// whatever the preflight asks for.
+	AllowHeaders []string
+
+	// AllowCredentials lets browsers send cookies and credentials. It needs
+	// explicit AllowOrigins: combined with "*", it would hand every website
+	// the user's session, so CORS panics.
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight. 0 leaves it to them.
+	MaxAge time.Duration
+}
+
+// CORS answers cross-origin requests from the allowed origins. Preflights
+// (OPTIONS with Access-Control-Request-Method) are answered 204 before
+// routing, advertising the methods the route table accepts for the path,
+// the same ones automatic OPTIONS lists in Allow, narrowed by AllowMethods.
+// Preflights for unknown paths get the usual 404. Other requests from an
+// allowed origin get Access-Control-Allow-Origin and go on as usual.
+//
+// See CORSReport to check what each route will advertise. It panics if
+// AllowCredentials is set with the "*" origin.
+func (a *App) CORS(config CORSConfig) {
+	if config.AllowCredentials && containsFold(config.AllowOrigins, "*") {
+		panic(`onion: CORS AllowCredentials can't be used with the "*" origin, list the origins`)
+	}
+	a.cors = &config
+	a.Pre(func(c *Context) {
+		origin := c.Request.Header.Get("Origin")
+		c.Response.Header().Add("Vary", "Origin")
+		if origin == "" || !config.allowsOrigin(origin) {
+			return
+		}
+
+		h := c.Response.Header()
+		if containsFold(config.AllowOrigins, "*") {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		requested := c.Request.Header.Get("Access-Control-Request-Method")
+		if c.Request.Method != http.MethodOptions || requested == "" {
+			return
+		}
+		a.routesMu.RLock()
+		methods := config.methods(a.allowedMethods(c.Scheme(), requestHost(c.Request), c.Request.URL.Path))
+		a.routesMu.RUnlock()
+		if len(methods) == 0 {
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if len(config.AllowHeaders) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(config.AllowHeaders, ", "))
+		} else if hdrs := c.Request.Header.Get("Access-Control-Request-Headers"); hdrs != "" {
+			h.Set("Access-Control-Allow-Headers", hdrs)
+		}
+		if config.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+		}
+		c.Response.WriteHeader(http.StatusNoContent)
+		c.Abort()
+	})
+}
+
+// allowsOrigin reports whether origin may make cross-origin requests.
+func (config *CORSConfig) allowsOrigin(origin string) bool {
+	for _, o := range config.AllowOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// methods narrows the route methods down to AllowMethods, if set.
+func (config *CORSConfig) methods(routeMethods []string) []string {
+	if len(config.AllowMethods) == 0 {
+		return routeMethods
+	}
+	var out []string
+	for _, m := range routeMethods {
+		if containsFold(config.AllowMethods, m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// CORSReport returns, for each route pattern, the methods a preflight will
+// get in Access-Control-Allow-Methods: the methods registered on the
+// pattern (plus HEAD with GET, and OPTIONS), narrowed by AllowMethods. It
+// helps debug why a browser preflight fails. Patterns that overlap (a
+// "/books/:id" and a "/books/new") are reported separately, while a
+// preflight for "/books/new" gets the methods of both. It returns nil if
+// CORS isn't configured.
+func (a *App) CORSReport() map[string][]string {
+	if a.cors == nil {
+		return nil
+	}
+	a.routesMu.RLock()
+	defer a.routesMu.RUnlock()
+
+	byPattern := make(map[string]map[string]bool)
+	for key := range a.routes {
+		if (key.method == http.MethodTrace && !a.allowTrace) || key.method == MethodAny {
+			continue
+		}
+		if byPattern[key.pattern] == nil {
+			byPattern[key.pattern] = map[string]bool{http.MethodOptions: true}
+		}
+		byPattern[key.pattern][key.method] = true
+		if key.method == http.MethodGet {
+			byPattern[key.pattern][http.MethodHead] = true
+		}
+	}
+
+	report := make(map[string][]string, len(byPattern))
+	for pattern, seen := range byPattern {
+		methods := make([]string, 0, len(seen))
+		for m := range seen {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		report[pattern] = a.cors.methods(methods)
+	}
+	return report
+}
@@ -0,0 +1,69 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestCORSPreflight ensures preflights advertise the route's methods and simple requests get the origin.
+func TestCORSPreflight(t *testing.T) {
+	app := New()
+	app.CORS(CORSConfig{AllowOrigins: []string{"https://app.example.com"}})
+	app.handle("GET", "/books", func(c *Context) { c.String(http.StatusOK, "books") })
+	app.handle("POST", "/books", func(c *Context) {})
+
+	req := httptest.NewRequest("OPTIONS", "/books", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code 204, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("Expected 'GET, HEAD, OPTIONS, POST', got '%s'", got)
+	}
+
+	req = httptest.NewRequest("GET", "/books", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Body.String() != "books" || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected no CORS headers for another origin, got %v", rec.Header())
+	}
+}
+
+// TestCORSReport ensures the report lists each pattern's methods, narrowed by AllowMethods.
+func TestCORSReport(t *testing.T) {
+	app := New()
+	if app.CORSReport() != nil {
+		t.Errorf("Expected no report without CORS")
+	}
+
+	app.CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowMethods: []string{"GET", "POST", "OPTIONS"}})
+	app.handle("GET", "/books", func(c *Context) {})
+	app.handle("POST", "/books", func(c *Context) {})
+	app.handle("DELETE", "/books/:id", func(c *Context) {})
+	app.handle("GET", "/books/:id", func(c *Context) {})
+
+	want := map[string][]string{
+		"/books":     {"GET", "OPTIONS", "POST"},
+		"/books/:id": {"GET", "OPTIONS"},
+	}
+	if got := app.CORSReport(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// TestCORSCredentialsAnyOrigin ensures credentials can't be combined with the "*" origin.
+func TestCORSCredentialsAnyOrigin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected AllowCredentials with \"*\" to panic")
+		}
+	}()
+	New().CORS(CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
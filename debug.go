@@ -0,0 +1,78 @@
+package onion
+
+import "net/http"
+
+// ----------------------------------------------------
+// Debug mode and introspection
+// ----------------------------------------------------
+
+// DebugMode turns development-only features on or off, like the endpoint
+// registered by DebugRoutes. It is off by default and can be toggled at any time.
+func (a *App) DebugMode(on bool) {
+	a.debug.Store(on)
+}
+
+// IsDebug reports whether DebugMode is on.
+func (a *App) IsDebug() bool {
+	return a.debug.Load()
+}
+
+// DebugRoutes registers GET path, which describes the app as JSON: the route
+// table, the per-route stats (see EnableStats) and the main settings. It
+// answers the app's 404 unless DebugMode is on, so leaving the call in a
+// production build exposes nothing.
+func (a *App) DebugRoutes(path string) {
+	a.handle(http.MethodGet, path, func(c *Context) {
+		if !a.IsDebug() {
+			a.notFound(c)
+			return
+		}
+		c.JSON(http.StatusOK, a.debugInfo())
+	})
+}
+
+type debugRoute struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Host    string `json:"host,omitempty"`
+	Handler string `json:"handler"`
+}
+
+type debugInfo struct {
+	Routes []debugRoute           `json:"routes"`
+	Stats  map[string]RouteStats  `json:"stats"`
+	Config map[string]interface{} `json:"config"`
+}
+
+func (a *App) debugInfo() debugInfo {
+	info := debugInfo{Routes: []debugRoute{}, Stats: a.Stats()}
+	for _, r := range a.namedRoutes() {
+		info.Routes = append(info.Routes, debugRoute{
+			Method:  r.key.method,
+			Pattern: r.key.pattern,
+			Host:    r.key.host,
+			Handler: r.handler,
+		})
+	}
+
+	a.serverMu.Lock()
+	keepAlives, maxConns := a.keepAlives, a.maxConns
+	a.serverMu.Unlock()
+
+	info.Config = map[string]interface{}{
+		"keep_alives":       keepAlives,
+		"max_connections":   maxConns,
+		"max_body_size":     a.maxBodySize,
+		"max_response_size": a.maxResponseSize,
+		"max_uri_length":    a.maxURILength,
+		"max_header_bytes":  a.maxHeaderBytes,
+		"timeout":           a.timeout.String(),
+		"watchdog":          a.watchdog.String(),
+		"allow_trace":       a.allowTrace,
+		"strict_patterns":   a.strictPatterns,
+		"problem_json":      a.problemJSON,
+		"stats_enabled":     a.stats.Load() != nil,
+		"middlewares":       len(a.middlewares),
+	}
+	return info
+}
@@ -0,0 +1,39 @@
+package onion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestDebugRoutes ensures the endpoint describes the app in debug mode and is a 404 otherwise.
+func TestDebugRoutes(t *testing.T) {
+	app := New()
+	app.DebugRoutes("/_debug")
+	app.handle("GET", "/books/:id", func(c *Context) {})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/_debug", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404 with debug off, got %d", rec.Code)
+	}
+
+	app.DebugMode(true)
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/_debug", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code 200 with debug on, got %d", rec.Code)
+	}
+
+	var info debugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("Expected JSON, got '%s'", rec.Body.String())
+	}
+	if len(info.Routes) != 2 || info.Routes[1].Pattern != "/books/:id" || info.Routes[1].Method != "GET" {
+		t.Errorf("Expected both routes to be listed, got %+v", info.Routes)
+	}
+	if _, ok := info.Config["max_body_size"]; !ok {
+		t.Errorf("Expected settings in the config, got %v", info.Config)
+	}
+}
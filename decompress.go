@@ -0,0 +1,60 @@
+package onion
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Compressed request bodies
+// ----------------------------------------------------
+
+// DefaultMaxDecompressedSize caps decompressed bodies when App.MaxBodySize isn't set.
+const DefaultMaxDecompressedSize = 10 << 20 // 10MB
+
+// DecompressRequest transparently decompresses request bodies sent with
+// "Content-Encoding: gzip" or "deflate", so BindJSON and friends see plain
+// bytes. Any other encoding is answered 415; a body that isn't valid for
+// its encoding, 400.
+//
+// To defuse decompression bombs, the decompressed body is capped at
+// App.MaxBodySize (DefaultMaxDecompressedSize if unset): reading past it
+// fails with *http.MaxBytesError, which the binders report as 413.
+func DecompressRequest() HandlerFunc {
+	return func(c *Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+			return
+		}
+
+		var body io.ReadCloser
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			body, err = gzip.NewReader(c.Request.Body)
+		case "deflate":
+			body, err = zlib.NewReader(c.Request.Body)
+		default:
+			c.String(http.StatusUnsupportedMediaType, "Unsupported Content-Encoding")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.String(http.StatusBadRequest, "Malformed "+encoding+" body")
+			c.Abort()
+			return
+		}
+
+		max := int64(DefaultMaxDecompressedSize)
+		if c.app.maxBodySize > 0 {
+			max = c.app.maxBodySize
+		}
+		c.Request.Body = http.MaxBytesReader(c.Response, body, max)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+	}
+}
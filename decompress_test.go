@@ -0,0 +1,84 @@
+package onion
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipped(s string) *bytes.Buffer {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return &buf
+}
+
+// TestDecompressRequest ensures gzip bodies are bound as plain JSON and unknown encodings get 415.
+func TestDecompressRequest(t *testing.T) {
+	app := New()
+	app.Use(DecompressRequest())
+	app.handle("POST", "/items", func(c *Context) {
+		var item bindItem
+		if err := c.BindJSON(&item); err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(http.StatusOK, item.Name)
+	})
+
+	req := httptest.NewRequest("POST", "/items", gzipped(`{"id":1,"name":"widget"}`))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "widget" {
+		t.Errorf("Expected the decompressed body to bind, got %d '%s'", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/items", strings.NewReader("xx"))
+	req.Header.Set("Content-Encoding", "br")
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status code 415, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/items", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code 400, got %d", rec.Code)
+	}
+}
+
+// TestDecompressRequestBomb ensures a small body inflating past the cap is a 413.
+func TestDecompressRequestBomb(t *testing.T) {
+	app := New()
+	app.MaxBodySize(64 << 10)
+	app.Use(DecompressRequest())
+	app.handle("POST", "/items", func(c *Context) {
+		var v interface{}
+		if err := c.BindJSON(&v); err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	bomb := gzipped(`"` + strings.Repeat("a", 10<<20) + `"`)
+	if bomb.Len() > 64<<10 {
+		t.Fatalf("Expected the compressed bomb to fit under the limit, got %d bytes", bomb.Len())
+	}
+
+	req := httptest.NewRequest("POST", "/items", bomb)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code 413, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,85 @@
+package onion
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ----------------------------------------------------
+// Response envelope
+// ----------------------------------------------------
+
+// SetMeta attaches key to the "meta" object of the response envelope, e.g.
+// pagination info. It has no effect without the Envelope middleware.
+func (c *Context) SetMeta(key string, value interface{}) {
+	if c.meta == nil {
+		c.meta = make(map[string]interface{})
+	}
+	c.meta[key] = value
+}
+
+// Envelope wraps JSON responses in a consistent shape, so handlers keep
+// returning plain data. Successful responses become
+//
+//	{"data": <body>, "meta": {...}}
+//
+// with "meta" present only if the handler called c.SetMeta, and responses
+// with a 4xx or 5xx status become
+//
+//	{"error": {"status": 404, "message": "Not Found"}}
+//
+// where the fields of an object body are kept and its "error" string is
+// renamed "message"; other bodies go under "detail". The status code is left
+// alone. Non-JSON responses, bodies over App.MaxTransformSize and flushed
+// responses pass through untouched.
+func Envelope() HandlerFunc {
+	return func(c *Context) {
+		orig := c.Response
+		tw := &transformWriter{ResponseWriter: orig, c: c}
+		tw.transformers = []responseTransformer{{
+			fn: func(c *Context, body []byte) []byte {
+				return envelope(tw.status, c.meta, body)
+			},
+			types: toSet([]string{"application/json"}),
+		}}
+		c.Response = tw
+		defer func() { c.Response = orig }()
+
+		c.Next()
+		tw.finish()
+	}
+}
+
+// envelope wraps a JSON body for the given status. A body that isn't valid
+// JSON is returned as is.
+func envelope(status int, meta map[string]interface{}, body []byte) []byte {
+	raw := json.RawMessage(bytes.TrimSpace(body))
+	if !json.Valid(raw) {
+		return body
+	}
+
+	var out interface{}
+	if status < 400 {
+		wrapped := map[string]interface{}{"data": raw}
+		if len(meta) > 0 {
+			wrapped["meta"] = meta
+		}
+		out = wrapped
+	} else {
+		var fields map[string]interface{}
+		if json.Unmarshal(raw, &fields) != nil || fields == nil {
+			fields = map[string]interface{}{"detail": raw}
+		} else if msg, ok := fields["error"].(string); ok {
+			delete(fields, "error")
+			fields["message"] = msg
+		}
+		fields["status"] = status
+		out = map[string]interface{}{"error": fields}
+	}
+
+	wrapped, err := json.Marshal(out)
+	if err != nil {
+		return body
+	}
+	return append(wrapped, '\n')
+}
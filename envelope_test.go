@@ -0,0 +1,41 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestEnvelope ensures success bodies go under "data" with meta, errors under "error".
+func TestEnvelope(t *testing.T) {
+	app := New()
+	app.Use(Envelope())
+	app.handle("GET", "/books", func(c *Context) {
+		c.SetMeta("page", 2)
+		c.JSON(http.StatusOK, []string{"dune"})
+	})
+	app.handle("GET", "/books/:id", func(c *Context) {
+		c.Error(NewHTTPError(http.StatusNotFound, "no such book"))
+	})
+	app.handle("GET", "/plain", func(c *Context) {
+		c.String(http.StatusOK, "hello")
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/books", http.StatusOK, `{"data":["dune"],"meta":{"page":2}}` + "\n"},
+		{"/books/9", http.StatusNotFound, `{"error":{"message":"no such book","status":404}}` + "\n"},
+		{"/plain", http.StatusOK, "hello"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+		if rec.Code != tt.code || rec.Body.String() != tt.body {
+			t.Errorf("%s: expected %d '%s', got %d '%s'", tt.path, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package onion
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ----------------------------------------------------
+// Errors (HTTPError and the centralized error handler)
+// ----------------------------------------------------
+
+// HTTPError is an error that carries the status code to respond with.
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+func (e HTTPError) Error() string {
+	return e.Message
+}
+
+// NewHTTPError creates an HTTPError. The message defaults to the status text.
+func NewHTTPError(code int, message ...string) HTTPError {
+	msg := http.StatusText(code)
+	if len(message) > 0 {
+		msg = message[0]
+	}
+	return HTTPError{Code: code, Message: msg}
+}
+
+// ErrorHandlerFunc turns an error into a response.
+type ErrorHandlerFunc func(c *Context, err error)
+
+// ErrorHandler sets the function used to respond to errors passed to c.Error.
+func (a *App) ErrorHandler(fn ErrorHandlerFunc) {
+	a.errorHandler = fn
+}
+
+// defaultErrorHandler responds with {"error": "..."}, plus "trace_id" when
+// the request is traced. Only HTTPError messages are shown to the client;
+// anything else becomes a generic 500.
+func defaultErrorHandler(c *Context, err error) {
+	he := asHTTPError(err)
+	if c.app != nil && c.app.problemJSON {
+		writeProblem(c, he)
+		return
+	}
+	body := map[string]string{"error": he.Message}
+	if c.traceID != "" {
+		body["trace_id"] = c.traceID
+	}
+	c.JSON(he.Code, body)
+}
+
+// ProblemJSON makes the default error handler answer with RFC 7807
+// application/problem+json bodies instead of {"error": "..."}:
+//
+//	{"type": "about:blank", "title": "Not Found", "status": 404,
+//	 "detail": "book not found", "instance": "/books/7"}
+func (a *App) ProblemJSON(v bool) {
+	a.problemJSON = v
+}
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	TraceID  string `json:"trace_id,omitempty"`
+}
+
+// writeProblem renders he as problem+json. The detail is left out when it
+// would only repeat the title.
+func writeProblem(c *Context, he HTTPError) {
+	p := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(he.Code),
+		Status:   he.Code,
+		Instance: c.Request.URL.Path,
+		TraceID:  c.traceID,
+	}
+	if he.Message != p.Title {
+		p.Detail = he.Message
+	}
+	c.Response.Header().Set("Content-Type", "application/problem+json")
+	c.Response.WriteHeader(he.Code)
+	json.NewEncoder(c.Response).Encode(p)
+}
+
+// asHTTPError unwraps an HTTPError from err, or maps it to a 500.
+func asHTTPError(err error) HTTPError {
+	var he HTTPError
+	if errors.As(err, &he) {
+		return he
+	}
+	var hep *HTTPError
+	if errors.As(err, &hep) && hep != nil {
+		return *hep
+	}
+	return NewHTTPError(http.StatusInternalServerError)
+}
+
+// Error records err on the context and hands it to the app's error handler.
+// Middlewares that run after the handler can inspect it via c.Errors().
+// If the response was already sent (see ResponseWritten), e.g. a stream that
+// failed halfway, the error is only recorded: writing an error page then
+// would corrupt the response.
+func (c *Context) Error(err error) {
+	c.errors = append(c.errors, err)
+	if c.ResponseWritten() {
+		return
+	}
+
+	handler := defaultErrorHandler
+	if c.app != nil && c.app.errorHandler != nil {
+		handler = c.app.errorHandler
+	}
+	handler(c, err)
+}
+
+// Errors returns the errors recorded during this request, oldest first.
+func (c *Context) Errors() []error {
+	return c.errors
+}
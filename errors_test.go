@@ -0,0 +1,121 @@
+package onion
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestErrorHandler ensures HTTPError keeps its status and custom handlers are used.
+func TestErrorHandler(t *testing.T) {
+	app := New()
+	app.handle("GET", "/missing", func(c *Context) {
+		c.Error(NewHTTPError(http.StatusNotFound, "book not found"))
+	})
+	app.handle("GET", "/bug", func(c *Context) {
+		c.Error(errors.New("secret detail"))
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "book not found") {
+		t.Errorf("Expected 404 'book not found', got %d '%s'", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/bug", nil))
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "secret") {
+		t.Errorf("Expected a generic 500, got %d '%s'", rec.Code, rec.Body.String())
+	}
+
+	app.ErrorHandler(func(c *Context, err error) {
+		c.String(http.StatusTeapot, "custom: "+err.Error())
+	})
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/bug", nil))
+	if rec.Code != http.StatusTeapot || rec.Body.String() != "custom: secret detail" {
+		t.Errorf("Expected custom error handler, got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
+
+// TestProblemJSON ensures errors are rendered as RFC 7807 problem details.
+func TestProblemJSON(t *testing.T) {
+	app := New()
+	app.ProblemJSON(true)
+	app.handle("GET", "/books/:id", func(c *Context) {
+		c.Error(NewHTTPError(http.StatusNotFound, "book not found"))
+	})
+	app.handle("GET", "/crash", func(c *Context) {
+		c.Error(errors.New("db: connection refused"))
+	})
+
+	tests := []struct {
+		path string
+		want Problem
+	}{
+		{"/books/7", Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "book not found", Instance: "/books/7"}},
+		{"/crash", Problem{Type: "about:blank", Title: "Internal Server Error", Status: 500, Instance: "/crash"}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+		if rec.Code != tt.want.Status {
+			t.Errorf("%s: expected status code %d, got %d", tt.path, tt.want.Status, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("%s: expected application/problem+json, got '%s'", tt.path, ct)
+		}
+		var got map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		for _, field := range []string{"type", "title", "status", "instance"} {
+			if _, ok := got[field]; !ok {
+				t.Errorf("%s: expected field '%s' in %v", tt.path, field, got)
+			}
+		}
+		var p Problem
+		json.Unmarshal(rec.Body.Bytes(), &p)
+		if p != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.path, tt.want, p)
+		}
+	}
+}
+
+// TestErrorAfterResponseWritten ensures the error handler is skipped once a response was sent.
+func TestErrorAfterResponseWritten(t *testing.T) {
+	handled := 0
+	var written bool
+	var status int
+	app := New()
+	app.ErrorHandler(func(c *Context, err error) {
+		handled++
+		c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	})
+	app.Use(func(c *Context) {
+		c.Next()
+		written, status = c.ResponseWritten(), c.ResponseStatus()
+	})
+	app.GET("/export", func(c *Context) {
+		if c.ResponseWritten() || c.ResponseStatus() != 0 {
+			t.Errorf("Expected nothing written yet, got %v (%d)", c.ResponseWritten(), c.ResponseStatus())
+		}
+		c.Response.WriteHeader(http.StatusOK)
+		c.Response.Write([]byte("id,title\n1,Dune\n"))
+		c.Error(errors.New("database went away"))
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/export", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "id,title\n1,Dune\n" {
+		t.Errorf("Expected the partial response untouched, got %d '%s'", rec.Code, rec.Body.String())
+	}
+	if handled != 0 {
+		t.Errorf("Expected the error handler to be skipped, got %d calls", handled)
+	}
+	if !written || status != http.StatusOK {
+		t.Errorf("Expected the response to be reported written with 200, got %v (%d)", written, status)
+	}
+}
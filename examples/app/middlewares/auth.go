@@ -9,6 +9,7 @@ func Auth(c *onion.Context) {
 	token := c.Request.Header.Get("X-Auth")
 	if token == "" {
 		c.String(http.StatusUnauthorized, "Unauthorized!")
+		c.Abort()
 		return
 	}
 }
@@ -0,0 +1,129 @@
+package onion
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Flash messages (redirect after POST)
+// ----------------------------------------------------
+
+// FlashCookie is the cookie carrying flash messages to the next request.
+const FlashCookie = "onion_flash"
+
+// Flash queues a one-time message under key ("success", "error", ...) for
+// the next request, typically the page a POST redirects to:
+//
+//	c.Flash("success", "Book saved")
+//	http.Redirect(c.Response, c.Request, "/books", http.StatusSeeOther)
+//
+// Onion has no server-side sessions, so messages travel in a cookie
+// (FlashCookie), signed with the app's flash secret (see App.FlashSecret).
+// The user can still read them, so keep them to text they may see, and
+// escape them when rendering.
+func (c *Context) Flash(key, message string) {
+	if c.flashes == nil {
+		c.flashes = make(map[string][]string)
+	}
+	c.flashes[key] = append(c.flashes[key], message)
+
+	data, _ := json.Marshal(c.flashes)
+	setFlashCookie(c.Response, &http.Cookie{
+		Name:     FlashCookie,
+		Value:    c.app.signFlash(data),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Flashes returns the messages queued by Flash on the previous request, by
+// key, and clears them so they're shown once. It returns nil if there are
+// none, or if the cookie's signature doesn't match.
+func (c *Context) Flashes() map[string][]string {
+	cookie, err := c.Request.Cookie(FlashCookie)
+	if err != nil {
+		return nil
+	}
+	if len(c.flashes) == 0 {
+		setFlashCookie(c.Response, &http.Cookie{Name: FlashCookie, Path: "/", MaxAge: -1})
+	}
+
+	data, ok := c.app.verifyFlash(cookie.Value)
+	if !ok {
+		return nil
+	}
+	var flashes map[string][]string
+	if json.Unmarshal(data, &flashes) != nil {
+		return nil
+	}
+	return flashes
+}
+
+// FlashSecret sets the key flash cookies are signed with (HMAC-SHA256). By
+// default each App picks a random one, so flashes don't survive a restart
+// and can't cross instances: set the same secret, at least 32 random bytes,
+// on every instance behind a load balancer. It panics on an empty secret.
+func (a *App) FlashSecret(secret []byte) {
+	if len(secret) == 0 {
+		panic("onion: empty flash secret")
+	}
+	a.flashSecret = secret
+}
+
+// signFlash encodes data as "payload.mac", both base64.
+func (a *App) signFlash(data []byte) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(data) + "." + enc.EncodeToString(a.flashMAC(data))
+}
+
+// verifyFlash decodes a cookie value made by signFlash, checking the MAC.
+func (a *App) verifyFlash(value string) ([]byte, bool) {
+	enc := base64.RawURLEncoding
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	data, err := enc.DecodeString(payload)
+	if err != nil {
+		return nil, false
+	}
+	mac, err := enc.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, a.flashMAC(data)) {
+		return nil, false
+	}
+	return data, true
+}
+
+func (a *App) flashMAC(data []byte) []byte {
+	mac := hmac.New(sha256.New, a.flashSecret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// randomKey returns 32 random bytes, the default flash secret.
+func randomKey() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}
+
+// setFlashCookie sets cookie, replacing any flash cookie set earlier in the
+// response.
+func setFlashCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	h := w.Header()
+	var kept []string
+	for _, v := range h.Values("Set-Cookie") {
+		if !strings.HasPrefix(v, FlashCookie+"=") {
+			kept = append(kept, v)
+		}
+	}
+	h["Set-Cookie"] = kept
+	http.SetCookie(w, cookie)
+}
@@ -0,0 +1,79 @@
+package onion
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestFlash ensures a flash set before a redirect is read once, then gone.
+func TestFlash(t *testing.T) {
+	app := New()
+	app.handle("POST", "/books", func(c *Context) {
+		c.Flash("success", "Book saved")
+		c.Flash("success", "Email sent")
+		http.Redirect(c.Response, c.Request, "/books", http.StatusSeeOther)
+	})
+	app.handle("GET", "/books", func(c *Context) {
+		msgs := c.Flashes()["success"]
+		if len(msgs) == 0 {
+			c.String(http.StatusOK, "none")
+			return
+		}
+		c.String(http.StatusOK, msgs[0]+", "+msgs[1])
+	})
+
+	srv := httptest.NewServer(app.mux)
+	defer srv.Close()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	read := func(resp *http.Response, err error) string {
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if got := read(client.Post(srv.URL+"/books", "text/plain", nil)); got != "Book saved, Email sent" {
+		t.Errorf("Expected the flashes after the redirect, got '%s'", got)
+	}
+	if got := read(client.Get(srv.URL + "/books")); got != "none" {
+		t.Errorf("Expected the flashes to be gone on the next read, got '%s'", got)
+	}
+}
+
+// TestFlashSigned ensures a tampered or foreign flash cookie is ignored, and a shared secret carries flashes across apps.
+func TestFlashSigned(t *testing.T) {
+	set, _ := NewTestContext("POST", "/", nil)
+	set.app.FlashSecret([]byte("0123456789abcdef0123456789abcdef"))
+	set.Flash("error", "Try again")
+	value := strings.TrimPrefix(strings.Split(set.Response.Header().Get("Set-Cookie"), ";")[0], FlashCookie+"=")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"error":["<script>"]}`))
+	payload, sig, _ := strings.Cut(value, ".")
+	tests := map[string]bool{
+		value:                     true,
+		forged:                    false, // unsigned
+		forged + "." + sig:        false, // someone else's signature
+		payload + "." + "AAAA":    false,
+		payload + "." + sig + "=": false,
+	}
+	for value, valid := range tests {
+		for _, secret := range []string{"0123456789abcdef0123456789abcdef", "another secret"} {
+			c, _ := NewTestContext("GET", "/", nil, WithTestHeader("Cookie", FlashCookie+"="+value))
+			c.app.FlashSecret([]byte(secret))
+			got := c.Flashes()
+			want := valid && secret == "0123456789abcdef0123456789abcdef"
+			if (got != nil) != want || (want && got["error"][0] != "Try again") {
+				t.Errorf("%s with secret %q: expected valid=%v, got %v", value, secret, want, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package onion
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ----------------------------------------------------
+// Changing the matched route (SetHandler, Forward)
+// ----------------------------------------------------
+
+// MaxForwards is how many times one request may be forwarded before Forward
+// gives up with 508 Loop Detected.
+const MaxForwards = 10
+
+// SetHandler replaces the route handler for this request, e.g. from an A/B
+// testing or feature-flag middleware. It must be called before the handler
+// runs; later calls have no effect on this request.
+func (c *Context) SetHandler(h HandlerFunc) {
+	if h == nil || len(c.handlers) == 0 || c.index >= len(c.handlers)-1 {
+		return
+	}
+	c.handlers[len(c.handlers)-1] = h
+}
+
+// Forward serves the request with the route matching method and path, as an
+// internal redirect: no round trip to the client and no second pass through
+// the middlewares. The target sees the new method, path and params; the
+// query, headers and body are unchanged. For example, in a handler:
+//
+//	if legacy {
+//		if err := c.Forward(http.MethodGet, "/v2/books/"+c.Param("id")); err != nil {
+//			c.Error(err)
+//		}
+//		return
+//	}
+//
+// The target is picked and checked as dispatch does: by Accept among
+// versioned routes (see WithAcceptVersion), then against WithProduces and
+// WithConsumes. It returns a 404 HTTPError if no route matches, the 406 or
+// 415 dispatch would answer, and 508 after MaxForwards forwards, which
+// catches routes forwarding to each other.
+func (c *Context) Forward(method, path string) error {
+	if c.forwards >= MaxForwards {
+		return NewHTTPError(http.StatusLoopDetected, fmt.Sprintf("forward loop: more than %d forwards", MaxForwards))
+	}
+	c.forwards++
+
+	a := c.app
+	a.routesMu.RLock()
+	key, entry, params, ok := a.lookup(method, c.Scheme(), requestHost(c.Request), path)
+	versionCode := 0
+	if ok && a.versioned {
+		key, entry, versionCode = a.selectVersion(key, entry, c.Request.Header.Get("Accept"))
+	}
+	a.routesMu.RUnlock()
+	if !ok {
+		return NewHTTPError(http.StatusNotFound)
+	}
+	if versionCode != 0 {
+		return NewHTTPError(versionCode)
+	}
+	if code := entry.negotiate(c.Request); code != 0 {
+		return NewHTTPError(code)
+	}
+
+	setRequestPath(c, path)
+	c.Request.Method = method
+	if entry.formParams {
+		params = formDecodeParams(key.pattern, c.Request, params)
+	}
+	if len(a.paramSources) > 0 {
+		params = a.sourceParams(c.Request, params)
+	}
+	c.setRoute(key, entry, params)
+	entry.handler(c)
+	return nil
+}
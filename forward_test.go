@@ -0,0 +1,130 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestForward ensures a request is served by the target route with its params.
+func TestForward(t *testing.T) {
+	app := New()
+	calls := 0
+	app.Use(func(c *Context) {
+		calls++
+	})
+	app.handle("GET", "/old/books/:id", func(c *Context) {
+		if err := c.Forward("GET", "/v2/books/"+c.Param("id")); err != nil {
+			c.Error(err)
+		}
+	})
+	app.handle("GET", "/v2/books/:bookId", func(c *Context) {
+		c.String(http.StatusOK, "v2 "+c.Param("bookId")+" at "+c.Request.URL.Path)
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/old/books/7", nil))
+	if rec.Body.String() != "v2 7 at /v2/books/7" {
+		t.Errorf("Expected the forwarded route to answer, got %d '%s'", rec.Code, rec.Body.String())
+	}
+	if calls != 1 {
+		t.Errorf("Expected middlewares to run once, ran %d times", calls)
+	}
+}
+
+// TestForwardLoop ensures routes forwarding to each other end in a 508.
+func TestForwardLoop(t *testing.T) {
+	app := New()
+	forwardTo := func(path string) HandlerFunc {
+		return func(c *Context) {
+			if err := c.Forward("GET", path); err != nil {
+				c.Error(err)
+			}
+		}
+	}
+	app.handle("GET", "/a", forwardTo("/b"))
+	app.handle("GET", "/b", forwardTo("/a"))
+	app.handle("GET", "/lost", forwardTo("/nowhere"))
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/a", nil))
+	if rec.Code != http.StatusLoopDetected {
+		t.Errorf("Expected status code 508, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/lost", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", rec.Code)
+	}
+}
+
+// TestSetHandler ensures a middleware can swap the route handler.
+func TestSetHandler(t *testing.T) {
+	app := New()
+	app.Use(func(c *Context) {
+		if c.Request.Header.Get("X-Variant") == "b" {
+			c.SetHandler(func(c *Context) {
+				c.String(http.StatusOK, "variant b")
+			})
+		}
+	})
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, "variant a")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Variant", "b")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Body.String() != "variant b" {
+		t.Errorf("Expected 'variant b', got '%s'", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Body.String() != "variant a" {
+		t.Errorf("Expected 'variant a', got '%s'", rec.Body.String())
+	}
+}
+
+// TestForwardNegotiates ensures the target is picked by Accept among versioned routes and checked against WithProduces.
+func TestForwardNegotiates(t *testing.T) {
+	app := New()
+	app.handle("GET", "/old/books", func(c *Context) {
+		if err := c.Forward("GET", "/books"); err != nil {
+			c.Error(err)
+		}
+	})
+	app.handle("GET", "/old/feed", func(c *Context) {
+		if err := c.Forward("GET", "/feed"); err != nil {
+			c.Error(err)
+		}
+	})
+	app.handle("GET", "/books", func(c *Context) { c.String(http.StatusOK, "v1") }, WithAcceptVersion("application/vnd.books.v1+json"))
+	app.handle("GET", "/books", func(c *Context) { c.String(http.StatusOK, "v2") }, WithAcceptVersion("application/vnd.books.v2+json"))
+	app.handle("GET", "/feed", func(c *Context) { c.String(http.StatusOK, "feed") }, WithProduces("application/atom+xml"))
+
+	tests := []struct {
+		path, accept string
+		code         int
+		body         string
+	}{
+		{"/old/books", "application/vnd.books.v1+json", http.StatusOK, "v1"},
+		{"/old/books", "application/vnd.books.v2+json", http.StatusOK, "v2"},
+		{"/old/books", "application/vnd.books.v9+json", http.StatusNotAcceptable, ""},
+		{"/old/feed", "text/html", http.StatusNotAcceptable, ""},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ { // the route table is a map: repeat to catch random picks
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			app.mux.ServeHTTP(rec, req)
+			if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+				t.Errorf("%s with %s: expected %d '%s', got %d '%s'", tt.path, tt.accept, tt.code, tt.body, rec.Code, rec.Body.String())
+				break
+			}
+		}
+	}
+}
@@ -1,3 +1,5 @@
 module onion
 
 go 1.23.0
+
+require golang.org/x/net v0.33.0
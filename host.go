@@ -0,0 +1,48 @@
+package onion
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Host-based routing
+// ----------------------------------------------------
+
+// Host returns a group whose routes only match requests for hostname, e.g.
+// "api.example.com" or "*.example.com" (any subdomain, but not example.com
+// itself). Routes added to it are registered on the app right away:
+//
+//	app.Host("api.example.com").GET("/books", ListBooks)
+//
+// When a host-specific route and a host-agnostic route both match, the
+// host-specific one wins. Ports are ignored and matching is case-insensitive.
+func (a *App) Host(hostname string) *RouteGroup {
+	return &RouteGroup{
+		host:   strings.ToLower(hostname),
+		routes: []Route{},
+		app:    a,
+	}
+}
+
+// requestHost returns r.Host without the port, lowercased.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// hostMatches reports whether host satisfies pattern ("" matches anything).
+func hostMatches(pattern, host string) bool {
+	if pattern == "" || pattern == host {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*.") {
+		suffix := pattern[1:] // ".example.com"
+		return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	}
+	return false
+}
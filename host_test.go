@@ -0,0 +1,99 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func hostRequest(app *App, host, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Host = host
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestHostExact ensures host-bound routes only match their host and win over host-agnostic ones.
+func TestHostExact(t *testing.T) {
+	app := New()
+	app.Host("api.example.com").GET("/books", func(c *Context) {
+		c.String(http.StatusOK, "api books")
+	})
+	app.Host("admin.example.com").GET("/stats", func(c *Context) {
+		c.String(http.StatusOK, "stats")
+	})
+	app.handle("GET", "/books", func(c *Context) {
+		c.String(http.StatusOK, "any books")
+	})
+
+	if body := hostRequest(app, "API.example.com:8080", "/books").Body.String(); body != "api books" {
+		t.Errorf("Expected 'api books', got '%s'", body)
+	}
+	if body := hostRequest(app, "www.example.com", "/books").Body.String(); body != "any books" {
+		t.Errorf("Expected 'any books', got '%s'", body)
+	}
+	if rec := hostRequest(app, "api.example.com", "/stats"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404 on the wrong host, got %d", rec.Code)
+	}
+}
+
+// TestHostWildcard ensures "*.example.com" matches subdomains but not the apex.
+func TestHostWildcard(t *testing.T) {
+	app := New()
+	app.Host("*.example.com").GET("/", func(c *Context) {
+		c.String(http.StatusOK, "tenant")
+	})
+
+	for _, host := range []string{"acme.example.com", "eu.acme.example.com"} {
+		if body := hostRequest(app, host, "/").Body.String(); body != "tenant" {
+			t.Errorf("Expected '%s' to match, got '%s'", host, body)
+		}
+	}
+	for _, host := range []string{"example.com", "acme.example.org", "badexample.com"} {
+		if rec := hostRequest(app, host, "/"); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected '%s' not to match, got %d", host, rec.Code)
+		}
+	}
+}
+
+// TestHostParams ensures a tenant from the host and an ID from the path reach the same handler.
+func TestHostParams(t *testing.T) {
+	app := New()
+	app.AddParamSource(HostParams(":tenant.example.com"))
+	app.AddParamSource(ParamSourceFunc(func(r *http.Request) []Param {
+		return []Param{{"id", "from-source"}, {"region", "eu"}}
+	}))
+	app.Host("*.example.com").GET("/invoices/:id", func(c *Context) {
+		c.String(http.StatusOK, c.Param("tenant")+" "+c.Param("id")+" "+c.Param("region"))
+	})
+
+	rec := hostRequest(app, "acme.example.com:8080", "/invoices/42")
+	if rec.Body.String() != "acme 42 eu" {
+		t.Errorf("Expected 'acme 42 eu', got '%s'", rec.Body.String())
+	}
+}
+
+// TestFormDecodedParams ensures "+" is a space only on routes with the option.
+func TestFormDecodedParams(t *testing.T) {
+	app := New()
+	app.handle("GET", "/search/:query", func(c *Context) {
+		c.String(http.StatusOK, c.Param("query"))
+	}, WithFormDecodedParams())
+	app.handle("GET", "/tags/:tag", func(c *Context) {
+		c.String(http.StatusOK, c.Param("tag"))
+	})
+
+	tests := map[string]string{
+		"/search/a+b":     "a b",
+		"/search/c%2B%2B": "c++",
+		"/search/x%20y":   "x y",
+		"/tags/a+b":       "a+b",
+		"/tags/x%20y":     "x y",
+	}
+	for path, want := range tests {
+		if body := hostRequest(app, "example.com", path).Body.String(); body != want {
+			t.Errorf("Path '%s': expected '%s', got '%s'", path, want, body)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package onion
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------
+// Idempotency keys
+// ----------------------------------------------------
+
+// IdempotencyHeader is the request header carrying the client's key.
+const IdempotencyHeader = "Idempotency-Key"
+
+// ErrIdempotencyInProgress is returned by IdempotencyStore.Begin while
+// another request with the same key is still running.
+var ErrIdempotencyInProgress = errors.New("onion: idempotent request already in progress")
+
+// IdempotentResponse is a recorded response, replayed for repeated keys.
+type IdempotentResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore keeps track of keys. Implement it on Redis or a database
+// to share keys between instances.
+type IdempotencyStore interface {
+	// Begin claims key. It returns the stored response if the key already
+	// completed, ErrIdempotencyInProgress if it's claimed by a running
+	// request, or (nil, nil) if the caller now owns the key.
+	Begin(key string) (*IdempotentResponse, error)
+	// Complete stores the response for a claimed key.
+	Complete(key string, resp *IdempotentResponse) error
+	// Release gives up a claimed key without storing anything, so the
+	// request can be retried.
+	Release(key string) error
+}
+
+// Idempotency replays the first response for requests carrying the same
+// Idempotency-Key, so clients can safely retry POST/PUT/PATCH/DELETE without
+// duplicating side effects. While the first request is still running, others
+// with the same key get 409 Conflict. 5xx responses are not stored, so a
+// failed attempt can be retried. Safe methods (GET, HEAD, OPTIONS) and
+// requests without the header pass through.
+//
+// Keys are scoped by method and path. Only the headers set after Idempotency
+// runs are stored, so per-request ones like X-Request-ID aren't replayed.
+// Replayed responses carry an "Idempotent-Replayed: true" header.
+func Idempotency(store IdempotencyStore) HandlerFunc {
+	return func(c *Context) {
+		key := c.Request.Header.Get(IdempotencyHeader)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			key = ""
+		}
+		if key == "" {
+			return
+		}
+		key = c.Request.Method + " " + c.Request.URL.Path + " " + key
+
+		stored, err := store.Begin(key)
+		if errors.Is(err, ErrIdempotencyInProgress) {
+			c.Error(NewHTTPError(http.StatusConflict, "a request with this idempotency key is in progress"))
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			h := c.Response.Header()
+			for k, v := range stored.Header {
+				h[k] = append([]string(nil), v...)
+			}
+			h.Set("Idempotent-Replayed", "true")
+			c.Response.WriteHeader(stored.Status)
+			c.Response.Write(stored.Body)
+			c.Abort()
+			return
+		}
+
+		orig := c.Response
+		before := orig.Header().Clone()
+		rec := &recordingWriter{ResponseWriter: orig}
+		c.Response = rec
+
+		completed := false
+		defer func() {
+			c.Response = orig
+			if !completed {
+				store.Release(key)
+			}
+		}()
+
+		c.Next()
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= 500 {
+			return
+		}
+		store.Complete(key, &IdempotentResponse{
+			Status: status,
+			Header: headersSetSince(before, orig.Header()),
+			Body:   rec.body.Bytes(),
+		})
+		completed = true
+	}
+}
+
+// recordingWriter passes writes through and keeps a copy of status and body.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+
+	limit     int  // stop copying the body past limit bytes, 0 for no limit
+	truncated bool // set once the copy stopped short
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.limit > 0 && w.body.Len()+len(b) > w.limit {
+		w.truncated = true
+	} else if !w.truncated {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (w *recordingWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ----------------------------------------------------
+// In-memory IdempotencyStore
+// ----------------------------------------------------
+
+type memoryIdempotencyEntry struct {
+	resp    *IdempotentResponse // nil while in progress
+	expires time.Time
+}
+
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+// NewMemoryIdempotencyStore keeps keys in memory for ttl (24h if ttl <= 0).
+// It suits a single instance; use a shared backend behind a load balancer.
+func NewMemoryIdempotencyStore(ttl time.Duration) IdempotencyStore {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &memoryIdempotencyStore{ttl: ttl, entries: make(map[string]*memoryIdempotencyEntry)}
+}
+
+func (s *memoryIdempotencyStore) Begin(key string) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	if e, ok := s.entries[key]; ok && now.After(e.expires) {
+		delete(s.entries, key)
+	}
+
+	if e, ok := s.entries[key]; ok {
+		if e.resp == nil {
+			return nil, ErrIdempotencyInProgress
+		}
+		return e.resp, nil
+	}
+	s.entries[key] = &memoryIdempotencyEntry{expires: now.Add(s.ttl)}
+	return nil, nil
+}
+
+// sweep drops expired entries, at most once a minute (or once per ttl if
+// shorter), so Begin isn't O(n) on every request. Begin also checks the
+// expiry of the key it looks up.
+func (s *memoryIdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < min(s.ttl, time.Minute) {
+		return
+	}
+	s.lastSweep = now
+	for k, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+func (s *memoryIdempotencyStore) Complete(key string, resp *IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = &memoryIdempotencyEntry{resp: resp, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,156 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestIdempotencyReplay ensures a repeated key replays the first response without rerunning the handler.
+func TestIdempotencyReplay(t *testing.T) {
+	var calls atomic.Int32
+	app := New()
+	app.Use(RequestID())
+	app.Use(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+	app.handle("POST", "/orders", func(c *Context) {
+		n := calls.Add(1)
+		c.Response.Header().Set("X-Order", strconv.Itoa(int(n)))
+		c.String(http.StatusCreated, "order "+strconv.Itoa(int(n)))
+	})
+
+	post := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/orders", nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post("abc")
+	second := post("abc")
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", calls.Load())
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != "order 1" || second.Header().Get("X-Order") != "1" {
+		t.Errorf("Expected the first response to be replayed, got %d '%s'", second.Code, second.Body.String())
+	}
+	if id := second.Header().Get(RequestIDHeader); id == "" || id == first.Header().Get(RequestIDHeader) {
+		t.Errorf("Expected the replay to keep its own request ID, got '%s'", id)
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected only the replay to be marked")
+	}
+
+	if post("other").Body.String() != "order 2" || post("").Body.String() != "order 3" {
+		t.Errorf("Expected new or missing keys to run the handler")
+	}
+}
+
+// TestIdempotencyConcurrent ensures a second request with an in-flight key gets 409.
+func TestIdempotencyConcurrent(t *testing.T) {
+	app := New()
+	app.Use(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	app.handle("POST", "/pay", func(c *Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "paid")
+	})
+
+	newReq := func() *http.Request {
+		req := httptest.NewRequest("POST", "/pay", nil)
+		req.Header.Set("Idempotency-Key", "k1")
+		return req
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, newReq())
+		done <- rec
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, newReq())
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code 409 while in progress, got %d", rec.Code)
+	}
+
+	close(release)
+	if first := <-done; first.Body.String() != "paid" {
+		t.Errorf("Expected the first request to complete, got '%s'", first.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, newReq())
+	if rec.Body.String() != "paid" || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected a replay after completion, got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
+
+// TestIdempotencyServerError ensures 5xx responses are not stored.
+func TestIdempotencyServerError(t *testing.T) {
+	var calls atomic.Int32
+	app := New()
+	app.Use(Idempotency(NewMemoryIdempotencyStore(time.Minute)))
+	app.handle("POST", "/flaky", func(c *Context) {
+		if calls.Add(1) == 1 {
+			c.String(http.StatusInternalServerError, "boom")
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/flaky", nil)
+		req.Header.Set("Idempotency-Key", "k")
+		app.mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected the retry to run the handler again, ran %d times", calls.Load())
+	}
+}
+
+// TestMemoryIdempotencyStoreExpiry ensures expired keys can be claimed again.
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	store := NewMemoryIdempotencyStore(20 * time.Millisecond)
+	store.Begin("a")
+	store.Complete("a", &IdempotentResponse{Status: http.StatusCreated})
+	if resp, _ := store.Begin("a"); resp == nil || resp.Status != http.StatusCreated {
+		t.Errorf("Expected the stored response, got %v", resp)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if resp, err := store.Begin("a"); resp != nil || err != nil {
+		t.Errorf("Expected an expired key to be claimable, got %v (%v)", resp, err)
+	}
+}
+
+// TestIdempotencyFlush ensures flushing still reaches the client behind the recorder.
+func TestIdempotencyFlush(t *testing.T) {
+	app := New()
+	app.Use(Idempotency(NewMemoryIdempotencyStore(time.Hour)))
+	app.POST("/events", func(c *Context) {
+		c.Response.Write([]byte("data: 1\n\n"))
+		c.Flush()
+		if err := http.NewResponseController(c.Response).Flush(); err != nil {
+			t.Errorf("Expected ResponseController to reach the writer, got %v", err)
+		}
+	})
+
+	req := httptest.NewRequest("POST", "/events", nil)
+	req.Header.Set(IdempotencyHeader, "k1")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if !rec.Flushed {
+		t.Error("Expected the response to be flushed")
+	}
+}
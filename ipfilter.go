@@ -0,0 +1,83 @@
+package onion
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ----------------------------------------------------
+// IP allow/deny lists
+// ----------------------------------------------------
+
+// IPFilterConfig configures IPFilter. Entries are single addresses
+// ("203.0.113.7", "2001:db8::1") or CIDR ranges ("10.0.0.0/8").
+type IPFilterConfig struct {
+	// Allow lists the clients that may connect. Empty means everyone not denied.
+	Allow []string
+
+	// Deny lists blocked clients. It wins over Allow.
+	Deny []string
+}
+
+// IPFilter answers 403 and aborts for clients that are denied, or not
+// allowed when Allow is set:
+//
+//	app.Use(onion.IPFilter(onion.IPFilterConfig{
+//		Allow: []string{"10.0.0.0/8", "192.168.1.20"},
+//		Deny:  []string{"10.6.6.0/24"},
+//	}))
+//
+// The client is c.ClientIP(), so behind a proxy, list it in
+// App.TrustedProxies to filter on the real client rather than the proxy. A
+// client IP that doesn't parse is
+// treated as not allowed. It panics on a malformed entry, so the mistake
+// shows up at startup.
+func IPFilter(config IPFilterConfig) HandlerFunc {
+	allow := parsePrefixes(config.Allow)
+	deny := parsePrefixes(config.Deny)
+
+	return func(c *Context) {
+		ip, err := netip.ParseAddr(c.ClientIP())
+		ip = ip.Unmap()
+		blocked := err != nil || containsAddr(deny, ip) || (len(allow) > 0 && !containsAddr(allow, ip))
+		if blocked {
+			c.String(http.StatusForbidden, "Forbidden")
+			c.Abort()
+		}
+	}
+}
+
+// parsePrefixes parses IPs and CIDR ranges, turning single IPs into /32 or /128.
+func parsePrefixes(entries []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				panic(fmt.Sprintf("onion: invalid IP filter range %q", e))
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(e)
+		if err != nil {
+			panic(fmt.Sprintf("onion: invalid IP filter address %q", e))
+		}
+		ip = ip.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes
+}
+
+// containsAddr reports whether any prefix contains ip.
+func containsAddr(prefixes []netip.Prefix, ip netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestIPFilter ensures CIDR ranges and single IPs are matched, with deny winning.
+func TestIPFilter(t *testing.T) {
+	app := New()
+	app.Use(IPFilter(IPFilterConfig{
+		Allow: []string{"10.0.0.0/8", "192.168.1.20", "2001:db8::/32"},
+		Deny:  []string{"10.6.6.0/24"},
+	}))
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := map[string]int{
+		"10.1.2.3:5000":      http.StatusOK,        // in an allowed range
+		"192.168.1.20:5000":  http.StatusOK,        // an allowed IP
+		"[2001:db8::7]:5000": http.StatusOK,        // an allowed IPv6 range
+		"10.6.6.9:5000":      http.StatusForbidden, // denied inside an allowed range
+		"192.168.1.21:5000":  http.StatusForbidden, // not on the allow list
+	}
+	for addr, code := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+
+		if rec.Code != code {
+			t.Errorf("%s: expected status code %d, got %d", addr, code, rec.Code)
+		}
+	}
+}
+
+// TestIPFilterDenyOnly ensures an empty allow list lets everyone in but the denied.
+func TestIPFilterDenyOnly(t *testing.T) {
+	app := New()
+	app.Use(IPFilter(IPFilterConfig{Deny: []string{"203.0.113.7"}}))
+	app.handle("GET", "/", func(c *Context) {})
+
+	for addr, code := range map[string]int{"203.0.113.7:1": http.StatusForbidden, "203.0.113.8:1": http.StatusOK} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+
+		if rec.Code != code {
+			t.Errorf("%s: expected status code %d, got %d", addr, code, rec.Code)
+		}
+	}
+}
+
+// TestIPFilterBehindProxy ensures the client is taken from X-Forwarded-For when the request comes from a trusted proxy.
+func TestIPFilterBehindProxy(t *testing.T) {
+	app := New()
+	app.TrustedProxies("10.0.0.0/8")
+	app.Use(IPFilter(IPFilterConfig{Deny: []string{"203.0.113.7"}}))
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, c.ClientIP())
+	})
+
+	tests := []struct {
+		remote, forwarded string
+		code              int
+		client            string
+	}{
+		{"10.0.0.2:5000", "198.51.100.1", http.StatusOK, "198.51.100.1"},
+		{"10.0.0.2:5000", "203.0.113.7", http.StatusForbidden, ""},
+		{"10.0.0.2:5000", "203.0.113.7, 10.0.0.9", http.StatusForbidden, ""},          // through two proxies
+		{"10.0.0.2:5000", "203.0.113.7, 198.51.100.1", http.StatusOK, "198.51.100.1"}, // forged first entry
+		{"10.0.0.2:5000", "", http.StatusOK, "10.0.0.2"},
+		{"198.51.100.1:5000", "203.0.113.8", http.StatusOK, "198.51.100.1"}, // untrusted sender
+		{"203.0.113.7:5000", "198.51.100.1", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != tt.code || (tt.client != "" && rec.Body.String() != tt.client) {
+			t.Errorf("%s via %s: expected %d '%s', got %d '%s'", tt.forwarded, tt.remote, tt.code, tt.client, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package onion
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Request limits (400 / 413 / 414 / 431)
+// ----------------------------------------------------
+
+// MaxBodySize caps the number of bytes read from a request body. A request
+// announcing a bigger Content-Length is answered 413 before routing; reading
+// past the limit otherwise fails with *http.MaxBytesError. 0 (the default) means unlimited.
+func (a *App) MaxBodySize(n int64) {
+	a.maxBodySize = n
+}
+
+// MaxResponseSize guards against runaway responses (an accidental endless
+// stream, a query without LIMIT): once a handler writes more than n body
+// bytes, the write is logged and the connection is aborted. 0 (the default)
+// means unlimited.
+//
+// The count is taken on what handlers write, before response transformers
+// or other buffering wrappers run, so the limit applies to the original body.
+func (a *App) MaxResponseSize(n int64) {
+	a.maxResponseSize = n
+}
+
+// MaxURILength answers 414 for requests whose URI (path + query) is longer
+// than n bytes. 0 (the default) means unlimited.
+func (a *App) MaxURILength(n int) {
+	a.maxURILength = n
+}
+
+// MaxHeaderBytes answers 431 for requests whose headers add up to more than
+// n bytes (counted as "Key: value\r\n" lines). 0 (the default) keeps the
+// net/http limit, http.DefaultMaxHeaderBytes (1MB).
+//
+// n is also set as the server's MaxHeaderBytes, so oversized headers are
+// rejected while they're read rather than buffered whole. net/http answers
+// those itself, with a plain 431 that RequestHeaderFieldsTooLargeHandler
+// can't customize; it allows some slack over n (4KB) before it does, so
+// only headers between the two limits get the custom handler.
+func (a *App) MaxHeaderBytes(n int) {
+	a.maxHeaderBytes = n
+}
+
+// MaxQueryParams answers 400 for requests with more than n query
+// parameters, before routing, so handlers never parse them into a map (a
+// cheap way to burn CPU with colliding keys). 0 (the default) means
+// unlimited.
+func (a *App) MaxQueryParams(n int) {
+	a.maxQueryParams = n
+}
+
+// MaxFormFields caps the fields of urlencoded form bodies: Context.ParseForm
+// (and Unmarshal, which uses it) fails with a 400 HTTPError past n fields.
+// The body has to be parsed to count them, so pair it with MaxBodySize. 0
+// (the default) means unlimited.
+func (a *App) MaxFormFields(n int) {
+	a.maxFormFields = n
+}
+
+// RequestEntityTooLargeHandler sets the response for bodies over MaxBodySize.
+func (a *App) RequestEntityTooLargeHandler(fn HandlerFunc) {
+	a.entityTooLarge = fn
+}
+
+// URITooLongHandler sets the response for URIs over MaxURILength.
+func (a *App) URITooLongHandler(fn HandlerFunc) {
+	a.uriTooLong = fn
+}
+
+// RequestHeaderFieldsTooLargeHandler sets the response for headers over MaxHeaderBytes.
+func (a *App) RequestHeaderFieldsTooLargeHandler(fn HandlerFunc) {
+	a.headersTooLarge = fn
+}
+
+// BadRequestHandler sets the response for requests whose path has malformed
+// percent-encoding, like "/books/%zz", or with more than MaxQueryParams
+// query parameters. The default is a plain 400.
+func (a *App) BadRequestHandler(fn HandlerFunc) {
+	a.badRequest = fn
+}
+
+// statusText is the default handler for the limit responses.
+func statusText(code int) HandlerFunc {
+	return func(c *Context) {
+		http.Error(c.Response, http.StatusText(code), code)
+	}
+}
+
+// checkLimits answers the request and returns false if it trips a limit.
+//
+// It also rejects malformed percent-encoding in the path. net/http answers
+// such requests 400 before they reach the app, but requests can get here
+// other ways (tests, outer handlers that build or rewrite them), and their
+// params must not be matched from a half-decoded path.
+func (a *App) checkLimits(w http.ResponseWriter, r *http.Request) bool {
+	switch {
+	case !validPathEncoding(r.URL):
+		a.badRequest(a.newContext(w, r, nil))
+	case a.maxURILength > 0 && len(r.URL.RequestURI()) > a.maxURILength:
+		a.uriTooLong(a.newContext(w, r, nil))
+	case a.maxQueryParams > 0 && countQueryParams(r.URL.RawQuery) > a.maxQueryParams:
+		a.badRequest(a.newContext(w, r, nil))
+	case a.maxHeaderBytes > 0 && headerSize(r.Header) > a.maxHeaderBytes:
+		a.headersTooLarge(a.newContext(w, r, nil))
+	case a.maxBodySize > 0 && r.ContentLength > a.maxBodySize:
+		a.entityTooLarge(a.newContext(w, r, nil))
+	default:
+		return true
+	}
+	return false
+}
+
+// validPathEncoding reports whether the raw path decodes cleanly.
+func validPathEncoding(u *url.URL) bool {
+	if u.RawPath == "" {
+		return true
+	}
+	_, err := url.PathUnescape(u.RawPath)
+	return err == nil
+}
+
+// countQueryParams counts the parameters of a raw query without parsing it.
+func countQueryParams(query string) int {
+	n := 0
+	for query != "" {
+		var param string
+		param, query, _ = strings.Cut(query, "&")
+		if param != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// headerSize approximates the wire size of the headers.
+func headerSize(h http.Header) int {
+	n := 0
+	for k, vs := range h {
+		for _, v := range vs {
+			n += len(k) + len(v) + 4 // ": " and "\r\n"
+		}
+	}
+	return n
+}
@@ -0,0 +1,195 @@
+package onion
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func limitsApp() *App {
+	app := New()
+	app.MaxBodySize(8)
+	app.MaxURILength(32)
+	app.MaxHeaderBytes(256)
+
+	jsonError := func(code int) HandlerFunc {
+		return func(c *Context) {
+			c.JSON(code, map[string]int{"error": code})
+		}
+	}
+	app.RequestEntityTooLargeHandler(jsonError(http.StatusRequestEntityTooLarge))
+	app.URITooLongHandler(jsonError(http.StatusRequestURITooLong))
+	app.RequestHeaderFieldsTooLargeHandler(jsonError(http.StatusRequestHeaderFieldsTooLarge))
+
+	app.Use(CacheBody())
+	app.handle("POST", "/upload", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	return app
+}
+
+// TestLimitHandlers trips each limit and expects the custom handler.
+func TestLimitHandlers(t *testing.T) {
+	bigBody := httptest.NewRequest("POST", "/upload", strings.NewReader("far too large"))
+
+	streamedBody := httptest.NewRequest("POST", "/upload", strings.NewReader("far too large"))
+	streamedBody.ContentLength = -1 // unknown length: caught while reading
+
+	longURI := httptest.NewRequest("POST", "/upload?q="+strings.Repeat("x", 64), nil)
+
+	bigHeaders := httptest.NewRequest("POST", "/upload", nil)
+	bigHeaders.Header.Set("X-Padding", strings.Repeat("x", 512))
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		code int
+	}{
+		{"content-length", bigBody, http.StatusRequestEntityTooLarge},
+		{"streamed body", streamedBody, http.StatusRequestEntityTooLarge},
+		{"uri", longURI, http.StatusRequestURITooLong},
+		{"headers", bigHeaders, http.StatusRequestHeaderFieldsTooLarge},
+		{"within limits", httptest.NewRequest("POST", "/upload", strings.NewReader("small")), http.StatusOK},
+	}
+
+	app := limitsApp()
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, tt.req)
+
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected status code %d, got %d", tt.name, tt.code, rec.Code)
+		}
+		if tt.code != http.StatusOK && !strings.Contains(rec.Body.String(), `"error"`) {
+			t.Errorf("%s: expected the custom JSON handler, got '%s'", tt.name, rec.Body.String())
+		}
+	}
+}
+
+// TestLimitDefaults ensures the default responses are plain text with the right status.
+func TestLimitDefaults(t *testing.T) {
+	app := New()
+	app.MaxURILength(8)
+	app.handle("GET", "/", func(c *Context) {})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/?q=very-long", nil))
+
+	if rec.Code != http.StatusRequestURITooLong || !strings.Contains(rec.Body.String(), "URI Too Long") {
+		t.Errorf("Expected a plain 414, got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
+
+// TestMaxResponseSize ensures writing past the cap aborts the connection and logs it.
+func TestMaxResponseSize(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.MaxResponseSize(1024)
+	app.handle("GET", "/small", func(c *Context) {
+		c.String(http.StatusOK, strings.Repeat("x", 1024))
+	})
+	app.handle("GET", "/runaway", func(c *Context) {
+		chunk := []byte(strings.Repeat("x", 100))
+		for {
+			c.Response.Write(chunk)
+		}
+	})
+
+	srv := httptest.NewServer(app.mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(body) != 1024 {
+		t.Errorf("Expected 1024 bytes under the cap, got %d", len(body))
+	}
+
+	resp, err = http.Get(srv.URL + "/runaway")
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Errorf("Expected the runaway response to be cut off")
+	}
+	if !strings.Contains(logs.String(), "GET /runaway exceeded 1024 bytes") {
+		t.Errorf("Expected the overflow to be logged, got '%s'", logs.String())
+	}
+}
+
+// TestMalformedPathEncoding ensures bad escapes get 400 in static and param positions.
+func TestMalformedPathEncoding(t *testing.T) {
+	app := New()
+	app.handle("GET", "/books/:id", func(c *Context) {
+		c.String(http.StatusOK, "book "+c.Param("id"))
+	})
+	app.handle("GET", "/a b/books", func(c *Context) {
+		c.String(http.StatusOK, "static")
+	})
+
+	request := func(rawPath string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.RawPath = rawPath
+		req.URL.Path = strings.ReplaceAll(rawPath, "%20", " ")
+		rec := httptest.NewRecorder()
+		// Straight to dispatch: net/http itself rejects these before routing
+		app.dispatch(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/books/%zz", "/books/1%2", "/%zz/books"} {
+		if rec := request(path); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code 400, got %d", path, rec.Code)
+		}
+	}
+	if rec := request("/a%20b/books"); rec.Code != http.StatusOK {
+		t.Errorf("Expected a valid escape to route, got %d", rec.Code)
+	}
+
+	app.BadRequestHandler(func(c *Context) {
+		c.String(http.StatusBadRequest, "malformed URL")
+	})
+	if rec := request("/books/%zz"); rec.Body.String() != "malformed URL" {
+		t.Errorf("Expected the custom handler, got '%s'", rec.Body.String())
+	}
+}
+
+// TestMaxQueryParamsAndFormFields ensures requests over the counts are answered 400.
+func TestMaxQueryParamsAndFormFields(t *testing.T) {
+	app := New()
+	app.MaxQueryParams(3)
+	app.MaxFormFields(2)
+	app.POST("/search", func(c *Context) {
+		if err := c.ParseForm(); err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		uri, form string
+		code      int
+	}{
+		{"/search?a=1&b=2&c=3", "x=1&y=2", http.StatusOK},
+		{"/search?a=1&&b=2&c=3&", "", http.StatusOK},
+		{"/search?a=1&b=2&c=3&a=4", "", http.StatusBadRequest},
+		{"/search", "x=1&x=2&y=3", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.uri, strings.NewReader(tt.form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: Expected status code %d, got %d", tt.uri, tt.form, tt.code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package onion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Declarative routes (LoadRoutes)
+// ----------------------------------------------------
+
+// RouteDef describes a route in data, e.g. decoded from a JSON or YAML file.
+// The handler is looked up by name among those passed to RegisterHandler.
+type RouteDef struct {
+	Method      string `json:"method" yaml:"method"`
+	Pattern     string `json:"pattern" yaml:"pattern"`
+	HandlerName string `json:"handler" yaml:"handler"`
+}
+
+// RegisterHandler makes fn available to LoadRoutes under name.
+func (a *App) RegisterHandler(name string, fn HandlerFunc) {
+	if fn == nil {
+		panic("onion: nil handler registered as " + name)
+	}
+	if a.namedHandlers == nil {
+		a.namedHandlers = make(map[string]HandlerFunc)
+	}
+	a.namedHandlers[name] = fn
+}
+
+// LoadRoutes registers routes from definitions. Everything is checked first:
+// unknown handler names, missing methods and malformed patterns (with the same
+// rules as StrictPatterns) are all reported together, and if there is any
+// problem no route is registered.
+func (a *App) LoadRoutes(defs []RouteDef) error {
+	var errs []error
+	routes := make([]Route, 0, len(defs))
+
+	for i, def := range defs {
+		if def.Method == "" {
+			errs = append(errs, fmt.Errorf("onion: route %d: missing method", i))
+		}
+		if err := validatePattern(def.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("onion: route %d: %s", i, strings.TrimPrefix(err.Error(), "onion: ")))
+		}
+		handler, ok := a.namedHandlers[def.HandlerName]
+		if !ok {
+			errs = append(errs, fmt.Errorf("onion: route %d: unknown handler %q", i, def.HandlerName))
+		}
+		routes = append(routes, Route{
+			Method:  strings.ToUpper(def.Method),
+			Pattern: def.Pattern,
+			Handler: handler,
+		})
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	a.UseRoutes(routes)
+	return nil
+}
@@ -0,0 +1,65 @@
+package onion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestLoadRoutes loads definitions from JSON and dispatches to the named handlers.
+func TestLoadRoutes(t *testing.T) {
+	app := New()
+	app.RegisterHandler("books.list", func(c *Context) { c.String(http.StatusOK, "all books") })
+	app.RegisterHandler("books.get", func(c *Context) { c.String(http.StatusOK, "book "+c.Param("bookId")) })
+
+	var defs []RouteDef
+	err := json.Unmarshal([]byte(`[
+		{"method": "GET", "pattern": "/books", "handler": "books.list"},
+		{"method": "get", "pattern": "/books/:bookId", "handler": "books.get"}
+	]`), &defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.LoadRoutes(defs); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := map[string]string{"/books": "all books", "/books/7": "book 7"}
+	for path, want := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Body.String() != want {
+			t.Errorf("Path '%s': expected '%s', got '%s'", path, want, rec.Body.String())
+		}
+	}
+}
+
+// TestLoadRoutesErrors ensures every problem is reported and nothing is registered.
+func TestLoadRoutesErrors(t *testing.T) {
+	app := New()
+	app.RegisterHandler("ok", func(c *Context) {})
+
+	err := app.LoadRoutes([]RouteDef{
+		{Method: "GET", Pattern: "/fine", HandlerName: "ok"},
+		{Method: "GET", Pattern: "/books", HandlerName: "missing"},
+		{Method: "GET", Pattern: "books", HandlerName: "ok"},
+		{Method: "", Pattern: "/x", HandlerName: "ok"},
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{`onion: route 1: unknown handler "missing"`, `onion: route 2: invalid pattern "books"`, "onion: route 3: missing method"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected '%s' in the error, got:\n%v", want, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/fine", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected no route to be registered, got %d", rec.Code)
+	}
+}
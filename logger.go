@@ -0,0 +1,219 @@
+package onion
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------
+// Pluggable logger
+// ----------------------------------------------------
+
+// LogPrinter is what Onion writes its own messages to. *log.Logger satisfies
+// it, and most structured loggers can be adapted in a couple of lines.
+type LogPrinter interface {
+	Printf(format string, v ...interface{})
+}
+
+// defaultLogger prints plain lines to stdout.
+var defaultLogger LogPrinter = log.New(os.Stdout, "", 0)
+
+// SetLogger replaces the logger used for the startup banner, the Logger
+// middleware and other framework messages. Passing nil restores the default (stdout).
+func (a *App) SetLogger(l LogPrinter) {
+	if l == nil {
+		l = defaultLogger
+	}
+	a.logger = l
+}
+
+// Logger returns the app's logger, so middleware can share it.
+func (a *App) Logger() LogPrinter {
+	return a.logger
+}
+
+// LogErrorsOnly makes the Logger middleware skip successful requests and
+// log only failures (5xx, plus 4xx with LogClientErrors), keeping production
+// logs quiet while still surfacing problems.
+func (a *App) LogErrorsOnly(v bool) {
+	a.logErrorsOnly = v
+}
+
+// LogClientErrors makes LogErrorsOnly also log 4xx responses.
+func (a *App) LogClientErrors(v bool) {
+	a.logClientErrors = v
+}
+
+// logMetaKey is the Route.Meta key for WithSilentLogging/WithVerboseLogging.
+const logMetaKey = "onion.logging"
+
+// WithSilentLogging keeps the Logger and LoggerJSON middleware quiet for a
+// route, e.g. a health check polled every second.
+func WithSilentLogging() RouteOption {
+	return WithMeta(logMetaKey, "silent")
+}
+
+// WithVerboseLogging adds the query string and User-Agent to the access log
+// line of a route, for routes that need closer watching.
+func WithVerboseLogging() RouteOption {
+	return WithMeta(logMetaKey, "verbose")
+}
+
+// logMode returns the route's logging mode: "silent", "verbose" or "".
+func logMode(c *Context) string {
+	mode, _ := c.Route().Meta[logMetaKey].(string)
+	return mode
+}
+
+// ----------------------------------------------------
+// Logger middleware (access log)
+// ----------------------------------------------------
+
+// LoggerConfig configures the Logger middleware.
+type LoggerConfig struct {
+	// Output receives one line per request. Defaults to the app's logger.
+	Output LogPrinter
+}
+
+// Logger logs one line per request once the handler is done, e.g.
+//
+//	GET /books/1 200 42B 1.3ms request_id=4f1c... user=7
+//
+// Fields attached with c.WithField are appended as key=value, sorted by key,
+// followed by the errors passed to c.Error, if any:
+//
+//	POST /orders 500 21B 4.1ms request_id=4f1c... errors="db: connection refused"
+//
+// See App.LogErrorsOnly to log failures only, and WithSilentLogging and
+// WithVerboseLogging for per-route control.
+func Logger(config LoggerConfig) HandlerFunc {
+	return func(c *Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.writer.status
+		mode := logMode(c)
+		if mode == "silent" || !c.app.shouldLog(status) {
+			return
+		}
+
+		out := config.Output
+		if out == nil {
+			out = c.app.logger
+		}
+		var detail string
+		if mode == "verbose" {
+			detail = fmt.Sprintf(" query=%q user_agent=%q", c.Request.URL.RawQuery, c.Request.UserAgent())
+		}
+		out.Printf("%s %s %d %dB %s%s%s%s",
+			c.Request.Method, c.Request.URL.Path, status, c.writer.size,
+			time.Since(start), detail, formatFields(c.fields), formatErrors(c.errors))
+	}
+}
+
+// formatErrors renders errors as ` errors="first; second"`.
+func formatErrors(errs []error) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf(" errors=%q", strings.Join(msgs, "; "))
+}
+
+// shouldLog applies LogErrorsOnly and LogClientErrors to a response status.
+func (a *App) shouldLog(status int) bool {
+	if !a.logErrorsOnly || status >= 500 {
+		return true
+	}
+	return a.logClientErrors && status >= 400
+}
+
+// LoggerJSON logs one JSON object per line to w once the handler is done,
+// for log pipelines. The keys are always present:
+//
+//	{"timestamp":"2024-05-01T12:00:00.123Z","method":"GET","path":"/books/1",
+//	 "route":"/books/:id","status":200,"bytes":42,"duration_ms":1.3,
+//	 "request_id":"4f1c...","client_ip":"203.0.113.7"}
+//
+// "route" is the matched pattern, "" if none matched. Fields attached with
+// c.WithField are added as extra keys (they can't replace the ones above),
+// and errors passed to c.Error under "errors". App.LogErrorsOnly applies,
+// as do WithSilentLogging and WithVerboseLogging ("query", "user_agent").
+func LoggerJSON(w io.Writer) HandlerFunc {
+	var mu sync.Mutex
+	return func(c *Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.writer.status
+		mode := logMode(c)
+		if mode == "silent" || !c.app.shouldLog(status) {
+			return
+		}
+
+		entry := make(map[string]interface{}, len(c.fields)+10)
+		for k, v := range c.fields {
+			entry[k] = v
+		}
+		entry["timestamp"] = start.UTC().Format(time.RFC3339Nano)
+		entry["method"] = c.Request.Method
+		entry["path"] = c.Request.URL.Path
+		entry["route"] = c.route.pattern
+		entry["status"] = status
+		entry["bytes"] = c.writer.size
+		entry["duration_ms"] = float64(time.Since(start).Microseconds()) / 1000
+		entry["request_id"] = c.requestID
+		entry["client_ip"] = c.ClientIP()
+		if mode == "verbose" {
+			entry["query"] = c.Request.URL.RawQuery
+			entry["user_agent"] = c.Request.UserAgent()
+		}
+		if len(c.errors) > 0 {
+			msgs := make([]string, len(c.errors))
+			for i, err := range c.errors {
+				msgs[i] = err.Error()
+			}
+			entry["errors"] = msgs
+		}
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			// A field that doesn't marshal shouldn't cost the whole line
+			line, _ = json.Marshal(map[string]interface{}{
+				"method": c.Request.Method, "path": c.Request.URL.Path,
+				"status": status, "log_error": err.Error(),
+			})
+		}
+		mu.Lock()
+		w.Write(append(line, '\n'))
+		mu.Unlock()
+	}
+}
+
+// formatFields renders fields as " k1=v1 k2=v2", sorted by key.
+func formatFields(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package onion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureLogger records every line logged through it.
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *captureLogger) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, "\n")
+}
+
+// TestLoggerFields ensures fields set by middleware appear in the access log line.
+func TestLoggerFields(t *testing.T) {
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.Use(Logger(LoggerConfig{}))
+	app.Use(RequestID())
+	app.Use(func(c *Context) {
+		c.WithField("user", 42).WithField("tenant", "acme")
+	})
+	app.handle("GET", "/books/:bookId", func(c *Context) {
+		c.String(http.StatusOK, "book")
+	})
+
+	req := httptest.NewRequest("GET", "/books/1", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	app.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := logger.String()
+	if !strings.HasPrefix(out, "GET /books/1 200 4B ") {
+		t.Errorf("Expected method, path, status and size in the log line, got '%s'", out)
+	}
+	if !strings.HasSuffix(out, " request_id=req-123 tenant=acme user=42") {
+		t.Errorf("Expected sorted fields at the end of the log line, got '%s'", out)
+	}
+}
+
+// TestLoggerOutput ensures a configured output overrides the app's logger.
+func TestLoggerOutput(t *testing.T) {
+	appLogger, own := &captureLogger{}, &captureLogger{}
+	app := New()
+	app.SetLogger(appLogger)
+	app.Use(Logger(LoggerConfig{Output: own}))
+	app.handle("GET", "/", func(c *Context) {})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if appLogger.String() != "" || !strings.HasPrefix(own.String(), "GET / 200") {
+		t.Errorf("Expected the line in the configured output only, got '%s' and '%s'", appLogger.String(), own.String())
+	}
+}
+
+// TestLogErrorsOnly ensures only failures are logged, with their errors attached.
+func TestLogErrorsOnly(t *testing.T) {
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.LogErrorsOnly(true)
+	app.Use(Logger(LoggerConfig{}))
+	app.handle("GET", "/ok", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	app.handle("GET", "/missing", func(c *Context) {
+		c.Error(NewHTTPError(http.StatusNotFound))
+	})
+	app.handle("GET", "/fail", func(c *Context) {
+		c.Error(fmt.Errorf("db: connection refused"))
+	})
+
+	for _, path := range []string{"/ok", "/missing", "/fail"} {
+		app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	out := logger.String()
+	if strings.Contains(out, "/ok") || strings.Contains(out, "/missing") {
+		t.Errorf("Expected 2xx and 4xx to be skipped, got '%s'", out)
+	}
+	if !strings.HasPrefix(out, "GET /fail 500") || !strings.HasSuffix(out, ` errors="db: connection refused"`) {
+		t.Errorf("Expected the 500 to be logged with its error, got '%s'", out)
+	}
+
+	app.LogClientErrors(true)
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+	if !strings.Contains(logger.String(), "GET /missing 404") {
+		t.Errorf("Expected the 404 to be logged with LogClientErrors, got '%s'", logger.String())
+	}
+}
+
+// TestRouteLogging ensures silent routes are skipped and verbose ones carry extra detail.
+func TestRouteLogging(t *testing.T) {
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.Use(Logger(LoggerConfig{}))
+	app.handle("GET", "/healthz", func(c *Context) {}, WithSilentLogging())
+	app.handle("GET", "/books", func(c *Context) {})
+	app.handle("GET", "/admin", func(c *Context) {}, WithVerboseLogging())
+
+	for _, target := range []string{"/healthz", "/books", "/admin?q=1"} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("User-Agent", "probe/1.0")
+		app.mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	out := logger.String()
+	if strings.Contains(out, "/healthz") {
+		t.Errorf("Expected the silent route to produce no log line, got '%s'", out)
+	}
+	if !strings.Contains(out, "GET /books 200") {
+		t.Errorf("Expected the normal route to be logged, got '%s'", out)
+	}
+	if !strings.Contains(out, `query="q=1" user_agent="probe/1.0"`) {
+		t.Errorf("Expected query and user agent on the verbose route, got '%s'", out)
+	}
+}
+
+// TestLoggerJSON ensures each entry is one JSON object with stable keys and types.
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	app := New()
+	app.Use(LoggerJSON(&buf))
+	app.Use(RequestID())
+	app.handle("GET", "/books/:id", func(c *Context) {
+		c.WithField("user", "ada").WithField("status", "overridden?")
+		c.String(http.StatusOK, "book")
+	})
+
+	req := httptest.NewRequest("GET", "/books/1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	app.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON line, got '%s' (%v)", buf.String(), err)
+	}
+
+	fields := map[string]string{
+		"method": "GET", "path": "/books/1", "route": "/books/:id",
+		"request_id": "req-1", "client_ip": "192.0.2.1", "user": "ada",
+	}
+	for k, want := range fields {
+		if entry[k] != want {
+			t.Errorf("Expected %s = '%s', got %v", k, want, entry[k])
+		}
+	}
+	if entry["status"] != float64(200) || entry["bytes"] != float64(4) {
+		t.Errorf("Expected numeric status and bytes, got %v and %v", entry["status"], entry["bytes"])
+	}
+	if _, ok := entry["duration_ms"].(float64); !ok {
+		t.Errorf("Expected a numeric duration_ms, got %v", entry["duration_ms"])
+	}
+	if ts, _ := entry["timestamp"].(string); !isRFC3339(ts) {
+		t.Errorf("Expected an RFC 3339 timestamp, got %v", entry["timestamp"])
+	}
+}
+
+func isRFC3339(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
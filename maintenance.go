@@ -0,0 +1,61 @@
+package onion
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ----------------------------------------------------
+// Maintenance mode
+// ----------------------------------------------------
+
+// MaintenanceConfig configures the Maintenance middleware.
+type MaintenanceConfig struct {
+	Body        string        // defaults to "Service Unavailable: down for maintenance"
+	ContentType string        // defaults to "text/plain; charset=utf-8"
+	RetryAfter  time.Duration // sent as Retry-After (in seconds, rounded up) when > 0
+	AllowPaths  []string      // exact paths still served, e.g. "/health"
+	AllowIPs    []string      // client IPs still served, e.g. your office
+}
+
+// Maintenance answers every request with 503 while enabled is set, except
+// for allowlisted paths and client IPs. Flip the flag at runtime (from a
+// signal handler or an admin endpoint) to enter or leave maintenance without
+// a restart.
+//
+// Register it with App.Pre so it answers before routing, including for
+// unknown paths and methods; with App.Use those still get their 404 or 405.
+func Maintenance(enabled *atomic.Bool, config MaintenanceConfig) HandlerFunc {
+	if config.Body == "" {
+		config.Body = "Service Unavailable: down for maintenance"
+	}
+	if config.ContentType == "" {
+		config.ContentType = "text/plain; charset=utf-8"
+	}
+	paths := toSet(config.AllowPaths)
+	ips := toSet(config.AllowIPs)
+
+	return func(c *Context) {
+		if !enabled.Load() || paths[c.Request.URL.Path] || ips[c.ClientIP()] {
+			return
+		}
+
+		if config.RetryAfter > 0 {
+			c.Response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(config.RetryAfter.Seconds()))))
+		}
+		c.Response.Header().Set("Content-Type", config.ContentType)
+		c.String(http.StatusServiceUnavailable, config.Body)
+		c.Abort()
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
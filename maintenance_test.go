@@ -0,0 +1,80 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMaintenance ensures toggling the flag flips the app into 503 except for allowlisted paths and IPs.
+func TestMaintenance(t *testing.T) {
+	var down atomic.Bool
+	app := New()
+	app.Use(Maintenance(&down, MaintenanceConfig{
+		RetryAfter: 2 * time.Minute,
+		AllowPaths: []string{"/health"},
+		AllowIPs:   []string{"10.0.0.1"},
+	}))
+	app.handle("GET", "/books", func(c *Context) { c.String(http.StatusOK, "books") })
+	app.handle("GET", "/health", func(c *Context) { c.String(http.StatusOK, "ok") })
+
+	get := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/books", "1.2.3.4:5678"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status code 200 before maintenance, got %d", rec.Code)
+	}
+
+	down.Store(true)
+
+	rec := get("/books", "1.2.3.4:5678")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code 503 during maintenance, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "120" {
+		t.Errorf("Expected Retry-After '120', got '%s'", rec.Header().Get("Retry-After"))
+	}
+	if rec := get("/health", "1.2.3.4:5678"); rec.Code != http.StatusOK {
+		t.Errorf("Expected allowlisted path to work, got %d", rec.Code)
+	}
+	if rec := get("/books", "10.0.0.1:5678"); rec.Code != http.StatusOK {
+		t.Errorf("Expected allowlisted IP to work, got %d", rec.Code)
+	}
+
+	down.Store(false)
+
+	if rec := get("/books", "1.2.3.4:5678"); rec.Code != http.StatusOK {
+		t.Errorf("Expected status code 200 after maintenance, got %d", rec.Code)
+	}
+}
+
+// TestMaintenancePre ensures that as a Pre hook, unknown paths and methods get the 503 too.
+func TestMaintenancePre(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	app := New()
+	app.Pre(Maintenance(&down, MaintenanceConfig{RetryAfter: 1500 * time.Millisecond}))
+	app.handle("GET", "/books", func(c *Context) { c.String(http.StatusOK, "books") })
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/books", nil),
+		httptest.NewRequest("GET", "/nope", nil),
+		httptest.NewRequest("DELETE", "/books", nil),
+	} {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s %s: Expected status code 503, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+		if rec.Header().Get("Retry-After") != "2" {
+			t.Errorf("Expected Retry-After rounded up to '2', got '%s'", rec.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package onion
+
+import (
+	"cmp"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Content negotiation (406 / 415)
+// ----------------------------------------------------
+
+// WithProduces declares the content types a route responds with. A request
+// whose Accept header allows none of them is answered 406 Not Acceptable
+// before the middlewares and handler run. A missing Accept header accepts anything.
+func WithProduces(contentTypes ...string) RouteOption {
+	return func(r *Route) {
+		r.Produces = append(r.Produces, contentTypes...)
+	}
+}
+
+// WithConsumes declares the request body content types a route accepts. A
+// request with a body of any other type (or none declared) is answered 415
+// Unsupported Media Type. Requests without a body are let through.
+func WithConsumes(contentTypes ...string) RouteOption {
+	return func(r *Route) {
+		r.Consumes = append(r.Consumes, contentTypes...)
+	}
+}
+
+// negotiate returns 406 or 415 if the request doesn't fit the route's
+// declared content types, 0 otherwise.
+func (e *routeEntry) negotiate(r *http.Request) int {
+	if len(e.consumes) > 0 && hasBody(r) {
+		mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if !containsFold(e.consumes, mt) {
+			return http.StatusUnsupportedMediaType
+		}
+	}
+	if len(e.produces) > 0 {
+		if accept := r.Header.Get("Accept"); accept != "" && !acceptsAny(accept, e.produces) {
+			return http.StatusNotAcceptable
+		}
+	}
+	return 0
+}
+
+// hasBody reports whether the request announces a body.
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength < 0 && r.Body != nil && r.Body != http.NoBody)
+}
+
+// acceptsAny reports whether an Accept header allows one of types. Ranges
+// like "text/*" and "*/*" are honored, and q=0 excludes a range.
+func acceptsAny(accept string, types []string) bool {
+	for _, rng := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(rng))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+			continue
+		}
+		for _, t := range types {
+			if mediaMatch(mt, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mediaMatch reports whether the media range rng ("*/*", "text/*" or an
+// exact type) covers the content type t.
+func mediaMatch(rng, t string) bool {
+	if rng == "*/*" {
+		return true
+	}
+	t, _, _ = mime.ParseMediaType(t)
+	if major, ok := strings.CutSuffix(rng, "/*"); ok {
+		return strings.HasPrefix(t, major+"/")
+	}
+	return rng == t
+}
+
+// containsFold reports whether list holds s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAcceptVersion declares the versioned media type a route serves, for
+// APIs versioned through the Accept header. Several routes can share a method
+// and pattern with different versions:
+//
+//	api.GET("/books", ListBooksV1, onion.WithAcceptVersion("application/vnd.myapi.v1+json"))
+//	api.GET("/books", ListBooksV2, onion.WithAcceptVersion("application/vnd.myapi.v2+json"))
+//
+// A request naming one of the versions in Accept gets that route. One that
+// doesn't name a version (no Accept, "*/*", "application/json") gets the
+// route registered without a version if there is one, else the latest: the
+// highest version, numbers compared as numbers (v10 is after v9). A request asking for an unknown
+// "application/vnd." type is answered 406.
+func WithAcceptVersion(mediaType string) RouteOption {
+	return func(r *Route) {
+		r.AcceptVersion = mediaType
+	}
+}
+
+// selectVersion picks among the routes sharing key's method, pattern, host
+// and scheme by the Accept header. It returns 406 if a version was asked for that
+// doesn't exist. The caller holds routesMu.
+func (a *App) selectVersion(key routeKey, entry *routeEntry, accept string) (routeKey, *routeEntry, int) {
+	var candidates []routeKey
+	for k := range a.routes {
+		if k.method == key.method && k.pattern == key.pattern && k.host == key.host && k.scheme == key.scheme {
+			candidates = append(candidates, k)
+		}
+	}
+	if len(candidates) == 1 && key.version == "" {
+		return key, entry, 0
+	}
+
+	askedVendor := false
+	for _, rng := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(rng))
+		if err != nil {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+			continue
+		}
+		for _, k := range candidates {
+			if k.version != "" && k.version == mt {
+				return k, a.routes[k], 0
+			}
+		}
+		askedVendor = askedVendor || strings.HasPrefix(mt, "application/vnd.")
+	}
+	if askedVendor {
+		return key, entry, http.StatusNotAcceptable
+	}
+
+	best := candidates[0]
+	for _, k := range candidates[1:] {
+		if best.version != "" && (k.version == "" || compareVersions(k.version, best.version) > 0) {
+			best = k
+		}
+	}
+	return best, a.routes[best], 0
+}
+
+// compareVersions orders version strings naturally: runs of digits compare
+// as numbers, so "vnd.myapi.v10+json" comes after "vnd.myapi.v9+json".
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		var ra, rb string
+		ra, a = versionRun(a)
+		rb, b = versionRun(b)
+		if isDigit(ra[0]) && isDigit(rb[0]) {
+			na, nb := strings.TrimLeft(ra, "0"), strings.TrimLeft(rb, "0")
+			if len(na) != len(nb) {
+				return cmp.Compare(len(na), len(nb))
+			}
+			ra, rb = na, nb
+		}
+		if c := strings.Compare(ra, rb); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// versionRun splits off the leading run of digits or non-digits of s.
+func versionRun(s string) (run, rest string) {
+	digit := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digit {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
@@ -0,0 +1,175 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWithProduces ensures incompatible Accept headers get 406 before the handler runs.
+func TestWithProduces(t *testing.T) {
+	app := New()
+	called := false
+	app.handle("GET", "/books", func(c *Context) {
+		called = true
+		c.JSON(http.StatusOK, []string{})
+	}, WithProduces("application/json"))
+
+	tests := []struct {
+		accept string
+		code   int
+	}{
+		{"", http.StatusOK},
+		{"application/json", http.StatusOK},
+		{"text/html, application/*;q=0.8", http.StatusOK},
+		{"*/*", http.StatusOK},
+		{"text/html", http.StatusNotAcceptable},
+		{"application/json;q=0, text/html", http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		called = false
+		req := httptest.NewRequest("GET", "/books", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("Accept %q: expected status code %d, got %d", tt.accept, tt.code, rec.Code)
+		}
+		if called != (tt.code == http.StatusOK) {
+			t.Errorf("Accept %q: expected handler called = %v", tt.accept, tt.code == http.StatusOK)
+		}
+	}
+}
+
+// TestWithConsumes ensures request bodies of other content types get 415.
+func TestWithConsumes(t *testing.T) {
+	app := New()
+	group := NewGroup("")
+	group.POST("/books", func(c *Context) {
+		c.String(http.StatusCreated, "created")
+	}, WithConsumes("application/json"))
+	app.UseRoutes(group.Routes())
+
+	tests := []struct {
+		contentType string
+		body        string
+		code        int
+	}{
+		{"application/json", `{}`, http.StatusCreated},
+		{"application/json; charset=utf-8", `{}`, http.StatusCreated},
+		{"text/plain", "hi", http.StatusUnsupportedMediaType},
+		{"", "hi", http.StatusUnsupportedMediaType},
+		{"", "", http.StatusCreated}, // no body, nothing to check
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/books", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != tt.code {
+			t.Errorf("Content-Type %q: expected status code %d, got %d", tt.contentType, tt.code, rec.Code)
+		}
+	}
+}
+
+// TestWithAcceptVersion ensures the Accept header picks between versions of one route.
+func TestWithAcceptVersion(t *testing.T) {
+	const v1, v2 = "application/vnd.myapi.v1+json", "application/vnd.myapi.v2+json"
+
+	app := New()
+	app.EnableStats(true)
+	api := app.Host("")
+	api.GET("/books", func(c *Context) {
+		c.String(http.StatusOK, "v1")
+	}, WithAcceptVersion(v1))
+	api.GET("/books", func(c *Context) {
+		c.String(http.StatusOK, "v2")
+	}, WithAcceptVersion(v2))
+
+	tests := []struct {
+		accept string
+		code   int
+		body   string
+	}{
+		{v1, http.StatusOK, "v1"},
+		{v2, http.StatusOK, "v2"},
+		{"application/vnd.myapi.v3+json, " + v1 + ";q=0.5", http.StatusOK, "v1"},
+		{"", http.StatusOK, "v2"}, // latest
+		{"*/*", http.StatusOK, "v2"},
+		{"application/vnd.myapi.v3+json", http.StatusNotAcceptable, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/books", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("Accept %q: expected %d '%s', got %d '%s'", tt.accept, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+
+	if app.Stats()["GET /books ("+v1+")"].Count != 2 {
+		t.Errorf("Expected stats per version, got %v", app.Stats())
+	}
+
+	// An unversioned route is the default
+	api.GET("/books", func(c *Context) {
+		c.String(http.StatusOK, "default")
+	})
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/books", nil))
+	if rec.Body.String() != "default" {
+		t.Errorf("Expected the unversioned route by default, got '%s'", rec.Body.String())
+	}
+}
+
+// TestAcceptVersionLatest ensures the latest version is picked numerically, among routes of the request's scheme.
+func TestAcceptVersionLatest(t *testing.T) {
+	app := New()
+	for _, v := range []string{"v2", "v10", "v9"} {
+		body := v
+		app.GET("/papers", func(c *Context) {
+			c.String(http.StatusOK, body)
+		}, WithAcceptVersion("application/vnd.myapi."+v+"+json"))
+	}
+	app.GET("/authors", func(c *Context) {
+		c.String(http.StatusOK, "v1")
+	}, WithAcceptVersion("application/vnd.myapi.v1+json"))
+	app.GET("/authors", func(c *Context) {
+		c.String(http.StatusOK, "v2 over https")
+	}, WithAcceptVersion("application/vnd.myapi.v2+json"), WithScheme("https"))
+
+	for path, want := range map[string]string{"/papers": "v10", "/authors": "v1"} {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Body.String() != want {
+			t.Errorf("%s: Expected '%s', got '%s'", path, want, rec.Body.String())
+		}
+	}
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v10", "v9", 1},
+		{"v2", "v10", -1},
+		{"v1.10", "v1.9", 1},
+		{"v01", "v1", 0},
+		{"v1", "v1beta", -1},
+		{"beta", "alpha", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q): Expected %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
@@ -1,75 +1,257 @@
 package onion
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// HandlerFunc defines the function signature for route handlers.
-type HandlerFunc func(*Context)
-
-// Context wraps http.ResponseWriter and *http.Request, plus path parameters.
-type Context struct {
-	Response http.ResponseWriter
-	Request  *http.Request
-	params   map[string]string
-}
-
-// String is a helper for sending plain text.
-func (c *Context) String(statusCode int, msg string) {
-	c.Response.WriteHeader(statusCode)
-	c.Response.Write([]byte(msg))
-}
-
-// JSON is a helper for sending JSON data.
-func (c *Context) JSON(statusCode int, data interface{}) {
-	c.Response.Header().Set("Content-Type", "application/json")
-	c.Response.WriteHeader(statusCode)
-	json.NewEncoder(c.Response).Encode(data)
-}
-
-// Param fetches a path param like ":bookId".
-func (c *Context) Param(key string) string {
-	return c.params[key]
-}
-
 // ----------------------------------------------------
 // App (the main Onion application struct)
 // ----------------------------------------------------
 
 type App struct {
 	mux         *http.ServeMux
+	pre         []HandlerFunc // run before routing, see Pre
 	middlewares []HandlerFunc
 	notFound    HandlerFunc
 
-	// We'll store routes here in a map, keyed by (method, pattern)
-	routes map[routeKey]HandlerFunc
+	methodNotAllowed HandlerFunc
+	errorHandler     ErrorHandlerFunc
+
+	// Extra params, see AddParamSource
+	paramSources []ParamSource
+
+	// Handlers available to LoadRoutes, by name
+	namedHandlers map[string]HandlerFunc
+
+	// We'll store routes here in a map, keyed by (method, pattern).
+	// Registration may happen while serving, so access goes through routesMu.
+	routesMu sync.RWMutex
+	routes   map[routeKey]*routeEntry
+
+	// Keys registered more than once, reported by Validate
+	duplicates []routeKey
+
+	// Set once a route uses WithAcceptVersion
+	versioned bool
+
+	// Server settings, applied when the app starts serving
+	serverMu   sync.Mutex
+	server     *http.Server
+	keepAlives bool
+	maxConns   int
+	draining   atomic.Bool // see Drain
+	tasks      []task
+	taskRunner *taskRunner // non-nil while tasks run
+
+	// Per-route statistics, nil unless EnableStats(true) was called
+	stats atomic.Pointer[statsTable]
+
+	// Recent requests, nil until RequestRing serves a request
+	recent atomic.Pointer[requestRing]
+
+	// Request limits, 0 means unlimited (or the default)
+	maxBodySize      int64
+	maxResponseSize  int64
+	maxURILength     int
+	maxQueryParams   int
+	maxFormFields    int
+	maxHeaderBytes   int
+	bindOrder        []BindSource          // see BindOrder
+	parsers          map[string]BodyParser // see RegisterParser
+	maxArrayElements int
+
+	entityTooLarge  HandlerFunc
+	uriTooLong      HandlerFunc
+	headersTooLarge HandlerFunc
+	badRequest      HandlerFunc
+
+	allowTrace     bool
+	cors           *CORSConfig // see CORS
+	spa            HandlerFunc // see SPA
+	trustedProxies []netip.Prefix
+	problemJSON    bool
+	panicStatus    map[reflect.Type]int // see MapPanic
+	debug          atomic.Bool
+	timeout        time.Duration
+	methodTimeouts map[string]time.Duration
+	watchdog       time.Duration
+	strictPatterns bool
+	wildcardSlash  bool // see WildcardLeadingSlash
+
+	httpClient  *http.Client // see SetHTTPClient
+	flashSecret []byte       // see FlashSecret
+
+	logger          LogPrinter
+	logErrorsOnly   bool
+	logClientErrors bool
+	banner          bool
+
+	// Response transformers, see UseResponseTransformer
+	transformers     []responseTransformer
+	maxTransformSize int
 }
 
 type routeKey struct {
 	method  string
 	pattern string
+	host    string // "" matches any host
+	scheme  string // "" matches any scheme, see WithScheme
+	version string // see WithAcceptVersion
 }
 
+// routeEntry is what the route table stores for each key. Whatever can be
+// worked out from the pattern is computed once, at registration.
+type routeEntry struct {
+	handler HandlerFunc
+	static  bool // no ":" or "*": matched by plain comparison, without a params map
+
+	produces []string // see WithProduces
+	consumes []string // see WithConsumes
+	meta     map[string]interface{}
+
+	constraints map[string]*regexp.Regexp // see WithConstraint
+	formParams  bool                      // see WithFormDecodedParams
+}
+
+// String renders the key as "GET /books/:bookId" or "GET api.example.com/books",
+// followed by the scheme and version in parentheses for routes that have
+// them: "GET /admin (https)", "GET /books (https, v2)".
+func (k routeKey) String() string {
+	s := k.method + " " + k.host + k.pattern
+	switch {
+	case k.scheme != "" && k.version != "":
+		s += " (" + k.scheme + ", " + k.version + ")"
+	case k.scheme != "":
+		s += " (" + k.scheme + ")"
+	case k.version != "":
+		s += " (" + k.version + ")"
+	}
+	return s
+}
+
+// MethodAny registers a route for every method that has no handler of its
+// own on the path. A route for the request method (or GET, for HEAD) always
+// wins over it, even if its pattern is less specific.
+const MethodAny = "*"
+
 // Route defines a single HTTP route.
 type Route struct {
 	Method  string
 	Pattern string
 	Handler HandlerFunc
+	Host    string // optional, e.g. "api.example.com" or "*.example.com"
+	Scheme  string // optional, "http" or "https", see WithScheme
+
+	// FormDecodedParams decodes params with "+" as a space, see WithFormDecodedParams
+	FormDecodedParams bool
+
+	// Content negotiation, see WithProduces, WithConsumes and WithAcceptVersion
+	Produces      []string
+	Consumes      []string
+	AcceptVersion string
+
+	// Meta holds arbitrary per-route settings for middleware to read through
+	// c.Route().Meta. See WithMeta.
+	Meta map[string]interface{}
+
+	// Constraints restricts params to values matching a regexp, see
+	// WithConstraint. A path whose param doesn't match isn't routed here.
+	Constraints map[string]*regexp.Regexp
+}
+
+// RouteOption configures a single route, e.g. WithProduces("application/json").
+type RouteOption func(*Route)
+
+// WithConstraint makes the route match only if param matches expr in full,
+// e.g. WithConstraint("id", `\d+`). It panics if expr doesn't compile.
+func WithConstraint(param, expr string) RouteOption {
+	re := compileConstraint(param, expr)
+	return func(r *Route) {
+		r.Constraints = withConstraint(r.Constraints, param, re)
+	}
+}
+
+// compileConstraint compiles expr anchored at both ends.
+func compileConstraint(param, expr string) *regexp.Regexp {
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		panic(fmt.Sprintf("onion: invalid constraint for %q: %v", param, err))
+	}
+	return re
+}
+
+// withConstraint returns a copy of constraints with param set to re.
+func withConstraint(constraints map[string]*regexp.Regexp, param string, re *regexp.Regexp) map[string]*regexp.Regexp {
+	out := make(map[string]*regexp.Regexp, len(constraints)+1)
+	for k, v := range constraints {
+		out[k] = v
+	}
+	out[param] = re
+	return out
+}
+
+// WithMeta sets Meta[key] on a route.
+func WithMeta(key string, value interface{}) RouteOption {
+	return func(r *Route) {
+		if r.Meta == nil {
+			r.Meta = make(map[string]interface{})
+		}
+		r.Meta[key] = value
+	}
 }
 
 // New creates a new Onion app
 func New() *App {
-	return &App{
+	a := &App{
 		mux:         http.NewServeMux(),
 		middlewares: []HandlerFunc{},
 		notFound: func(c *Context) {
 			http.NotFound(c.Response, c.Request)
 		},
-		routes: make(map[routeKey]HandlerFunc),
+		methodNotAllowed: func(c *Context) {
+			http.Error(c.Response, "405 method not allowed", http.StatusMethodNotAllowed)
+		},
+		entityTooLarge:  statusText(http.StatusRequestEntityTooLarge),
+		uriTooLong:      statusText(http.StatusRequestURITooLong),
+		headersTooLarge: statusText(http.StatusRequestHeaderFieldsTooLarge),
+		badRequest:      statusText(http.StatusBadRequest),
+		routes:          make(map[routeKey]*routeEntry),
+		keepAlives:      true,
+		logger:          defaultLogger,
+		banner:          true,
+		flashSecret:     randomKey(),
+
+		maxTransformSize: DefaultMaxTransformSize,
 	}
+
+	// Register exactly one fallback route: "/"
+	a.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		a.dispatch(w, r)
+	})
+
+	return a
+}
+
+// Default creates an app with the usual middleware already registered:
+// Logger, then Recovery, then RequestID. Logger comes first so it still
+// logs the 500 when Recovery turns a panic into one. Use New for a bare app.
+func Default() *App {
+	a := New()
+	a.Use(Logger(LoggerConfig{}))
+	a.Use(Recovery())
+	a.Use(RequestID())
+	return a
 }
 
 // Use registers a middleware that will run before route handlers.
@@ -82,34 +264,168 @@ func (a *App) NotFoundHandler(fn HandlerFunc) {
 	a.notFound = fn
 }
 
+// MethodNotAllowedHandler sets a custom 405, used when the path exists but not
+// for the request method. The Allow header is already set when it runs.
+func (a *App) MethodNotAllowedHandler(fn HandlerFunc) {
+	a.methodNotAllowed = fn
+}
+
+// Handle registers a handler for any method, including custom ones.
+func (a *App) Handle(method, pattern string, handler HandlerFunc, opts ...RouteOption) {
+	a.handle(method, pattern, handler, opts...)
+}
+
+// GET registers a route straight on the app, for when a group is overkill:
+//
+//	app.GET("/health", health).
+//		POST("/login", login)
+//
+// It returns the app for chaining. POST, PUT, PATCH and DELETE work the same.
+func (a *App) GET(pattern string, handler HandlerFunc, opts ...RouteOption) *App {
+	a.handle(http.MethodGet, pattern, handler, opts...)
+	return a
+}
+
+func (a *App) POST(pattern string, handler HandlerFunc, opts ...RouteOption) *App {
+	a.handle(http.MethodPost, pattern, handler, opts...)
+	return a
+}
+
+func (a *App) PUT(pattern string, handler HandlerFunc, opts ...RouteOption) *App {
+	a.handle(http.MethodPut, pattern, handler, opts...)
+	return a
+}
+
+func (a *App) PATCH(pattern string, handler HandlerFunc, opts ...RouteOption) *App {
+	a.handle(http.MethodPatch, pattern, handler, opts...)
+	return a
+}
+
+func (a *App) DELETE(pattern string, handler HandlerFunc, opts ...RouteOption) *App {
+	a.handle(http.MethodDelete, pattern, handler, opts...)
+	return a
+}
+
 // UseRoutes loads multiple route slices (like BookRoutes, UserRoutes).
 func (a *App) UseRoutes(routeGroups ...[]Route) {
 	for _, group := range routeGroups {
 		for _, r := range group {
-			a.handle(r.Method, r.Pattern, r.Handler)
+			a.addRoute(r)
 		}
 	}
 }
 
+// Reload atomically replaces the whole route table, e.g. after a SIGHUP or a
+// feature-flag change. Requests already running finish on the old handlers;
+// new requests see the new table. Connections are not affected.
+func (a *App) Reload(routeGroups ...[]Route) {
+	routes := make(map[routeKey]*routeEntry)
+	var duplicates []routeKey
+	versioned := false
+	for _, group := range routeGroups {
+		for _, r := range group {
+			key, entry := a.newRouteEntry(r)
+			if _, ok := routes[key]; ok {
+				duplicates = append(duplicates, key)
+			}
+			routes[key] = entry
+			versioned = versioned || key.version != ""
+		}
+	}
+
+	a.routesMu.Lock()
+	a.routes = routes
+	a.duplicates = duplicates
+	a.versioned = versioned
+	a.routesMu.Unlock()
+}
+
 // handle just stores the route in our map. We do the actual matching in dispatch().
-func (a *App) handle(method, pattern string, handler HandlerFunc) {
-	a.routes[routeKey{method, pattern}] = handler
+// Like http.ServeMux, it panics on a nil handler so the mistake shows up at startup.
+func (a *App) handle(method, pattern string, handler HandlerFunc, opts ...RouteOption) {
+	r := Route{Method: method, Pattern: pattern, Handler: handler}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	a.addRoute(r)
 }
 
-// Run starts the server. Here we register one wildcard handleFunc to dispatch.
-func (a *App) Run(addr string) error {
-	fmt.Println("Onion server running on", addr)
+func (a *App) addRoute(r Route) {
+	key, entry := a.newRouteEntry(r)
 
-	// Register exactly one fallback route: "/"
-	a.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		a.dispatch(w, r)
-	})
+	a.routesMu.Lock()
+	if _, ok := a.routes[key]; ok {
+		a.duplicates = append(a.duplicates, key)
+	}
+	a.routes[key] = entry
+	a.versioned = a.versioned || key.version != ""
+	a.routesMu.Unlock()
+}
+
+// AddRoute registers a single route. It is safe to call while the server is
+// running, e.g. for routes discovered at runtime.
+func (a *App) AddRoute(r Route) {
+	a.addRoute(r)
+}
 
-	return http.ListenAndServe(addr, a.mux)
+// newRouteEntry validates r and turns it into a route table entry.
+func (a *App) newRouteEntry(r Route) (routeKey, *routeEntry) {
+	key := routeKey{
+		method:  r.Method,
+		pattern: r.Pattern,
+		host:    strings.ToLower(r.Host),
+		scheme:  strings.ToLower(r.Scheme),
+		version: strings.ToLower(r.AcceptVersion),
+	}
+	if r.Handler == nil {
+		panic("onion: nil handler for " + key.String())
+	}
+	if a.strictPatterns {
+		if err := validatePattern(r.Pattern); err != nil {
+			panic(err.Error())
+		}
+	}
+	return key, &routeEntry{
+		handler:     r.Handler,
+		static:      !strings.ContainsAny(r.Pattern, ":*"),
+		produces:    r.Produces,
+		consumes:    r.Consumes,
+		meta:        r.Meta,
+		constraints: r.Constraints,
+		formParams:  r.FormDecodedParams,
+	}
+}
+
+// match checks the entry's pattern against path, skipping the params
+// machinery entirely for static patterns.
+func (e *routeEntry) match(pattern, path string) ([]Param, bool) {
+	if e.static {
+		return nil, pattern == path
+	}
+	params, ok := matchWithParams(pattern, path)
+	if !ok || len(e.constraints) == 0 {
+		return params, ok
+	}
+	for _, p := range params {
+		if re := e.constraints[p.Key]; re != nil && !re.MatchString(p.Value) {
+			return nil, false
+		}
+	}
+	return params, true
 }
 
 // dispatch finds a matching route by (method, path), extracts params, executes middlewares, etc.
 func (a *App) dispatch(w http.ResponseWriter, r *http.Request) {
+	if !a.checkLimits(w, r) {
+		return
+	}
+	if len(a.pre) > 0 {
+		var ok bool
+		if r, ok = a.runPre(w, r); !ok {
+			return
+		}
+	}
+
 	reqPath := r.URL.Path
 	reqMethod := r.Method
 
@@ -120,62 +436,394 @@ func (a *App) dispatch(w http.ResponseWriter, r *http.Request) {
 	//   1) Scan all known routes for any that match the method
 	//   2) For each route with same method, check if the path matches (with param placeholders)
 	//   3) If found, parse out params and call its handler
-	//   4) Otherwise fallback to 404
-
-	for key, handler := range a.routes {
-		if key.method == reqMethod {
-			params, ok := matchWithParams(key.pattern, reqPath)
-			if ok {
-				c := &Context{
-					Response: w,
-					Request:  r,
-					params:   params,
-				}
-
-				// Middlewares
-				for _, mw := range a.middlewares {
-					mw(c)
-				}
-
-				// Handler
-				handler(c)
-				return
-			}
+	//   4) HEAD falls back to the GET route, then any method to a MethodAny route
+	//   5) If the path exists under other methods => auto OPTIONS or 405
+	//   6) Otherwise fallback to 404
+
+	host, scheme := requestHost(r), a.requestScheme(r)
+	traceBlocked := reqMethod == http.MethodTrace && !a.allowTrace
+
+	// Only the lookup holds the lock, so Reload never waits on a slow handler
+	a.routesMu.RLock()
+	var key routeKey
+	var entry *routeEntry
+	var params []Param
+	ok := false
+	versionCode := 0
+	if !traceBlocked {
+		key, entry, params, ok = a.lookup(reqMethod, scheme, host, reqPath)
+		if ok && a.versioned {
+			key, entry, versionCode = a.selectVersion(key, entry, r.Header.Get("Accept"))
+		}
+	}
+	var allowed []string
+	if !ok {
+		allowed = a.allowedMethods(scheme, host, reqPath)
+	}
+	a.routesMu.RUnlock()
+
+	if ok {
+		if versionCode != 0 {
+			a.newContext(w, r, params).Error(NewHTTPError(versionCode))
+			return
+		}
+		if code := entry.negotiate(r); code != 0 {
+			a.newContext(w, r, params).Error(NewHTTPError(code))
+			return
+		}
+		if entry.formParams {
+			params = formDecodeParams(key.pattern, r, params)
+		}
+		if len(a.paramSources) > 0 {
+			params = a.sourceParams(r, params)
 		}
+		a.serve(w, r, key, entry, params)
+		return
 	}
 
-	// If we reach here, no route matched => 404
-	a.notFound(&Context{Response: w, Request: r})
+	if traceBlocked {
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+		}
+		a.methodNotAllowed(a.newContext(w, r, nil))
+		return
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		if reqMethod == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		a.methodNotAllowed(a.newContext(w, r, nil))
+		return
+	}
+
+	// If we reach here, no route matched => the SPA, if any, or 404
+	if a.spa != nil {
+		a.spa(a.newContext(w, r, nil))
+		return
+	}
+	a.notFound(a.newContext(w, r, nil))
 }
 
-// matchWithParams checks if the "pattern" (like "/books/:bookId") matches "path" ("/books/123").
-// If it matches, returns (map[string]string, true). If not, returns (nil, false).
-func matchWithParams(pattern, path string) (map[string]string, bool) {
-	pParts := strings.Split(pattern, "/")
-	pathParts := strings.Split(path, "/")
+// lookup is match plus the method fallbacks: HEAD is served by the GET
+// route, and any method by a MethodAny route. The caller holds routesMu.
+func (a *App) lookup(method, scheme, host, path string) (routeKey, *routeEntry, []Param, bool) {
+	key, entry, params, ok := a.match(method, scheme, host, path)
+	if !ok && method == http.MethodHead {
+		// net/http drops the body for HEAD responses, so the GET handler is fine
+		key, entry, params, ok = a.match(http.MethodGet, scheme, host, path)
+	}
+	if !ok {
+		key, entry, params, ok = a.match(MethodAny, scheme, host, path)
+	}
+	return key, entry, params, ok
+}
+
+// match returns the route registered for method whose pattern matches path.
+// Routes bound to a matching host win over host-agnostic ones, and routes bound
+// to the request scheme over scheme-agnostic ones, then the most
+// specific pattern wins: "/books/new" over "/books/:id" over "/books/*rest".
+func (a *App) match(method, scheme, host, path string) (routeKey, *routeEntry, []Param, bool) {
+	var best routeKey
+	var bestEntry *routeEntry
+	var bestParams []Param
+	found := false
+
+	for key, entry := range a.routes {
+		if key.method != method || !hostMatches(key.host, host) || (key.scheme != "" && key.scheme != scheme) {
+			continue
+		}
+		params, ok := entry.match(key.pattern, path)
+		if !ok {
+			continue
+		}
+		if !found || betterMatch(key, best, len(entry.constraints) > 0, len(bestEntry.constraints) > 0) {
+			best, bestEntry, bestParams, found = key, entry, params, true
+		}
+	}
+	if !found {
+		return routeKey{}, nil, nil, false
+	}
+	return best, bestEntry, bestParams, true
+}
 
-	// They must have the same number of segments
-	if len(pParts) != len(pathParts) {
-		return nil, false
+// betterMatch reports whether route a should be preferred over route b when
+// both match the same request. Between identical shapes, a route with
+// constraints wins, since it was made to match a narrower set of paths.
+func betterMatch(a, b routeKey, aConstrained, bConstrained bool) bool {
+	if (a.host != "") != (b.host != "") {
+		return a.host != ""
+	}
+	if (a.scheme != "") != (b.scheme != "") {
+		return a.scheme != ""
+	}
+	aParts := strings.Split(a.pattern, "/")
+	bParts := strings.Split(b.pattern, "/")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if ra, rb := segmentRank(aParts[i]), segmentRank(bParts[i]); ra != rb {
+			return ra > rb
+		}
+	}
+	if len(aParts) != len(bParts) {
+		return len(aParts) > len(bParts)
 	}
+	if aConstrained != bConstrained {
+		return aConstrained
+	}
+	return a.pattern < b.pattern // stable choice for identical shapes
+}
 
-	params := make(map[string]string)
+// segmentRank orders segment kinds: literal > mixed (":name.:ext") > ":param" > "*wildcard".
+func segmentRank(seg string) int {
+	switch {
+	case strings.HasPrefix(seg, "*"):
+		return 0
+	case strings.HasPrefix(seg, ":") && isParamName(seg[1:]):
+		return 1
+	case strings.Contains(seg, ":"):
+		return 2
+	default:
+		return 3
+	}
+}
 
-	for i := 0; i < len(pParts); i++ {
-		pp := pParts[i]
-		pa := pathParts[i]
+// allowedMethods lists every method that would be accepted for path, sorted and
+// de-duplicated. HEAD is implied by GET, and OPTIONS is always answered.
+func (a *App) allowedMethods(scheme, host, path string) []string {
+	seen := map[string]bool{}
+	for key, entry := range a.routes {
+		if (key.method == http.MethodTrace && !a.allowTrace) || key.method == MethodAny {
+			continue
+		}
+		if !hostMatches(key.host, host) || (key.scheme != "" && key.scheme != scheme) {
+			continue
+		}
+		if _, ok := entry.match(key.pattern, path); ok {
+			seen[key.method] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	if seen[http.MethodGet] {
+		seen[http.MethodHead] = true
+	}
+	seen[http.MethodOptions] = true
+
+	methods := make([]string, 0, len(seen))
+	for m := range seen {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// newContext wraps the writer and prepares a fresh Context for one request.
+func (a *App) newContext(w http.ResponseWriter, r *http.Request, params []Param) *Context {
+	rw := newResponseWriter(w)
+	if a.maxResponseSize > 0 {
+		rw.maxSize = a.maxResponseSize
+		rw.tooLarge = func() {
+			a.logger.Printf("onion: response to %s %s exceeded %d bytes, connection aborted",
+				r.Method, r.URL.Path, a.maxResponseSize)
+		}
+	}
+	return &Context{
+		Response: rw,
+		Request:  r,
+		params:   params,
+		app:      a,
+		writer:   rw,
+		index:    -1,
+	}
+}
 
-		if strings.HasPrefix(pp, ":") {
+// serve runs the middlewares and the handler for a matched route.
+func (a *App) serve(w http.ResponseWriter, r *http.Request, key routeKey, entry *routeEntry, params []Param) {
+	if a.maxBodySize > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, a.maxBodySize)
+	}
+
+	if d := a.requestTimeout(r.Method); d > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	var tw *transformWriter
+	if len(a.transformers) > 0 {
+		tw = &transformWriter{ResponseWriter: w, transformers: a.transformers}
+		w = tw
+	}
+
+	c := a.newContext(w, r, params)
+	c.setRoute(key, entry, params)
+	start := time.Now()
+	if tw != nil {
+		tw.c = c
+	}
+
+	handler := entry.handler
+	if handler == nil {
+		// handle() rejects nil handlers, but don't let a bad route table crash the request
+		handler = func(c *Context) {
+			c.Error(fmt.Errorf("onion: nil handler for %s", key))
+		}
+	}
+	if a.watchdog > 0 {
+		handler = a.withWatchdog(key, handler, a.watchdog)
+	}
+
+	// Middlewares first, then the handler. Each step can stop the rest via c.Abort().
+	c.handlers = make([]HandlerFunc, 0, len(a.middlewares)+1)
+	c.handlers = append(c.handlers, a.middlewares...)
+	c.handlers = append(c.handlers, handler)
+	c.Next()
+	if tw != nil {
+		tw.finish()
+	}
+
+	if stats := a.stats.Load(); stats != nil {
+		stats.record(key.String(), c.writer.status, time.Since(start))
+	}
+}
+
+// matchWithParams checks if the "pattern" (like "/books/:bookId") matches "path" ("/books/123").
+// If it matches, returns the params in pattern order and true. If not, returns (nil, false).
+// A final "*name" segment catches the rest of the path, possibly empty:
+// "/static/*filepath" matches "/static/css/app.css" with filepath = "css/app.css".
+func matchWithParams(pattern, path string) ([]Param, bool) {
+	// Walk both strings segment by segment with strings.Cut rather than
+	// splitting them, so nothing is allocated unless a param is captured.
+	var params []Param
+	pRest, sRest := pattern, path
+
+	for {
+		pp, pNext, pMore := strings.Cut(pRest, "/")
+
+		if strings.HasPrefix(pp, "*") {
+			// wildcard: takes the rest of the path
+			return append(params, Param{pp[1:], sRest}), true
+		}
+
+		sp, sNext, sMore := strings.Cut(sRest, "/")
+
+		if strings.HasPrefix(pp, ":") && isParamName(pp[1:]) {
 			// param placeholder
-			key := strings.TrimPrefix(pp, ":")
-			params[key] = pa
-		} else if pp != pa {
+			params = append(params, Param{pp[1:], sp})
+		} else if strings.Contains(pp, ":") {
+			// mixed segment like ":name.:ext"
+			if !matchSegment(parseSegment(pp), sp, &params) {
+				return nil, false
+			}
+		} else if pp != sp {
 			// mismatch
 			return nil, false
 		}
+
+		if !pMore || !sMore {
+			if pMore && strings.HasPrefix(pNext, "*") && !strings.Contains(pNext, "/") {
+				// "/static/*filepath" also matches "/static", with an empty filepath
+				return append(params, Param{pNext[1:], ""}), true
+			}
+			// They must have the same number of segments
+			if pMore != sMore {
+				return nil, false
+			}
+			return params, true
+		}
+		pRest, sRest = pNext, sNext
 	}
+}
 
-	return params, true
+// segPart is a piece of a mixed segment: either a literal or a ":param".
+type segPart struct {
+	literal string
+	param   string
+}
+
+// parseSegment splits a segment like ":name.:ext" or "v:version.json" into
+// literal and param parts. Param names are letters, digits and underscores.
+func parseSegment(seg string) []segPart {
+	var parts []segPart
+	for seg != "" {
+		i := strings.IndexByte(seg, ':')
+		if i != 0 {
+			if i < 0 {
+				i = len(seg)
+			}
+			parts = append(parts, segPart{literal: seg[:i]})
+			seg = seg[i:]
+			continue
+		}
+		j := 1
+		for j < len(seg) && isParamChar(seg[j]) {
+			j++
+		}
+		parts = append(parts, segPart{param: seg[1:j]})
+		seg = seg[j:]
+	}
+	return parts
+}
+
+// matchSegment matches one path segment against parsed parts, filling params.
+//
+// Limitations: params are matched greedily and must be non-empty, so
+// ":name.:ext" on "archive.tar.gz" gives name=archive.tar, ext=gz; and two
+// params must be separated by a literal (":a:b" never matches).
+func matchSegment(parts []segPart, s string, params *[]Param) bool {
+	if len(parts) == 0 {
+		return s == ""
+	}
+
+	p := parts[0]
+	if p.param == "" {
+		if !strings.HasPrefix(s, p.literal) {
+			return false
+		}
+		return matchSegment(parts[1:], s[len(p.literal):], params)
+	}
+
+	if len(parts) == 1 {
+		if s == "" {
+			return false
+		}
+		*params = append(*params, Param{p.param, s})
+		return true
+	}
+
+	next := parts[1].literal
+	if next == "" {
+		return false
+	}
+	// Greedy: try the last occurrence of the following literal first
+	// The rest of the segment is matched first, so insert this param before its params.
+	n := len(*params)
+	for i := strings.LastIndex(s, next); i > 0; i = strings.LastIndex(s[:i], next) {
+		if matchSegment(parts[1:], s[i:], params) {
+			*params = slices.Insert(*params, n, Param{p.param, s[:i]})
+			return true
+		}
+	}
+	return false
+}
+
+func isParamName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isParamChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isParamChar(b byte) bool {
+	return b == '_' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
 }
 
 // ----------------------------------------------------
@@ -183,8 +831,14 @@ func matchWithParams(pattern, path string) (map[string]string, bool) {
 // ----------------------------------------------------
 
 type RouteGroup struct {
-	prefix string
-	routes []Route
+	prefix      string
+	host        string
+	scheme      string
+	routes      []Route
+	constraints map[string]*regexp.Regexp // see Constrain
+
+	// Set for groups created from an App (e.g. app.Host): routes register right away
+	app *App
 }
 
 // NewGroup("books") => prefix = "books"
@@ -196,43 +850,112 @@ func NewGroup(prefix string) *RouteGroup {
 }
 
 // GET etc. Just appends a Route with the correct method, path, handler
-func (rg *RouteGroup) GET(pattern string, handler HandlerFunc) *RouteGroup {
-	rg.routes = append(rg.routes, Route{
-		Method:  http.MethodGet,
-		Pattern: "/" + rg.prefix + pattern,
-		Handler: handler,
-	})
-	return rg
+func (rg *RouteGroup) GET(pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	return rg.Handle(http.MethodGet, pattern, handler, opts...)
 }
 
-func (rg *RouteGroup) POST(pattern string, handler HandlerFunc) *RouteGroup {
-	rg.routes = append(rg.routes, Route{
-		Method:  http.MethodPost,
-		Pattern: "/" + rg.prefix + pattern,
+func (rg *RouteGroup) POST(pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	return rg.Handle(http.MethodPost, pattern, handler, opts...)
+}
+
+func (rg *RouteGroup) PUT(pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	return rg.Handle(http.MethodPut, pattern, handler, opts...)
+}
+
+func (rg *RouteGroup) DELETE(pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	return rg.Handle(http.MethodDelete, pattern, handler, opts...)
+}
+
+// Handle appends a Route for any method, including custom ones like "PURGE".
+func (rg *RouteGroup) Handle(method, pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	r := Route{
+		Method:  method,
+		Pattern: rg.fullPattern(pattern),
 		Handler: handler,
-	})
+		Host:    rg.host,
+		Scheme:  rg.scheme,
+	}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	rg.routes = append(rg.routes, r)
+	if rg.app != nil {
+		rg.app.addRoute(rg.constrain(r))
+	}
 	return rg
 }
 
-func (rg *RouteGroup) PUT(pattern string, handler HandlerFunc) *RouteGroup {
-	rg.routes = append(rg.routes, Route{
-		Method:  http.MethodPut,
-		Pattern: "/" + rg.prefix + pattern,
-		Handler: handler,
-	})
+// Constrain makes every :param route in the group match only if param
+// matches expr in full, so the regexp isn't repeated on each route:
+//
+//	onion.NewGroup("users").Constrain("userId", `\d+`).
+//		GET("/:userId", getUser).
+//		GET("/:userId/orders", listOrders)
+//
+// A route's own WithConstraint for the same param wins. Groups created from
+// an App (app.Host) register routes as they're added, so call Constrain
+// first there. It panics if expr doesn't compile.
+func (rg *RouteGroup) Constrain(param, expr string) *RouteGroup {
+	rg.constraints = withConstraint(rg.constraints, param, compileConstraint(param, expr))
 	return rg
 }
 
-func (rg *RouteGroup) DELETE(pattern string, handler HandlerFunc) *RouteGroup {
-	rg.routes = append(rg.routes, Route{
-		Method:  http.MethodDelete,
-		Pattern: "/" + rg.prefix + pattern,
-		Handler: handler,
-	})
+// constrain adds the group constraints to r, keeping the route's own.
+func (rg *RouteGroup) constrain(r Route) Route {
+	for param, re := range rg.constraints {
+		if _, ok := r.Constraints[param]; !ok {
+			r.Constraints = withConstraint(r.Constraints, param, re)
+		}
+	}
+	return r
+}
+
+// fullPattern joins the group prefix and a route pattern.
+func (rg *RouteGroup) fullPattern(pattern string) string {
+	if rg.prefix == "" {
+		if pattern == "" {
+			return "/"
+		}
+		return pattern
+	}
+	return "/" + rg.prefix + pattern
+}
+
+// Match appends one Route per listed method, all sharing the same handler.
+func (rg *RouteGroup) Match(methods []string, pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	for _, m := range methods {
+		rg.Handle(m, pattern, handler, opts...)
+	}
 	return rg
 }
 
-// Routes returns the final []Route
+// anyMethods are the methods registered by Any.
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete,
+	http.MethodConnect, http.MethodTrace,
+}
+
+// Any appends a Route for every standard HTTP method.
+func (rg *RouteGroup) Any(pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	return rg.Match(anyMethods, pattern, handler, opts...)
+}
+
+// Fallback appends a Route that catches every method without its own
+// handler on the pattern, so a path can have dedicated GET/POST handlers and
+// send everything else to one place. Inspect c.Request.Method inside.
+func (rg *RouteGroup) Fallback(pattern string, handler HandlerFunc, opts ...RouteOption) *RouteGroup {
+	return rg.Handle(MethodAny, pattern, handler, opts...)
+}
+
+// Routes returns the final []Route, with the group's constraints applied.
 func (rg *RouteGroup) Routes() []Route {
-	return rg.routes
+	if len(rg.constraints) == 0 {
+		return rg.routes
+	}
+	routes := make([]Route, len(rg.routes))
+	for i, r := range rg.routes {
+		routes[i] = rg.constrain(r)
+	}
+	return routes
 }
@@ -1,8 +1,10 @@
 package onion
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -132,3 +134,415 @@ func TestMultipleRoutes(t *testing.T) {
 		t.Errorf("Expected body 'Route 2', got '%s'", rec2.Body.String())
 	}
 }
+
+// TestFlush ensures flushed data reaches the client before the handler returns.
+func TestFlush(t *testing.T) {
+	app := New()
+
+	release := make(chan struct{})
+	app.handle("GET", "/stream", func(c *Context) {
+		c.Response.Write([]byte("first;"))
+		c.Flush()
+		c.Flush() // safe to call again
+		<-release
+		c.Response.Write([]byte("second"))
+	})
+
+	srv := httptest.NewServer(app.mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	buf := make([]byte, len("first;"))
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "first;" {
+		t.Errorf("Expected 'first;' before the handler returned, got '%s'", buf)
+	}
+
+	close(release)
+	rest, _ := io.ReadAll(resp.Body)
+	if string(rest) != "second" {
+		t.Errorf("Expected 'second', got '%s'", rest)
+	}
+}
+
+// TestMethodNotAllowed ensures a known path with the wrong method gets a 405 and a sorted Allow header.
+func TestMethodNotAllowed(t *testing.T) {
+	app := New()
+	app.UseRoutes(NewGroup("items").
+		GET("", func(c *Context) { c.String(http.StatusOK, "list") }).
+		POST("", func(c *Context) { c.String(http.StatusOK, "create") }).
+		Handle("PURGE", "", func(c *Context) { c.String(http.StatusOK, "purged") }).
+		Match([]string{http.MethodPost, http.MethodPut}, "/:id", func(c *Context) {}).
+		Routes())
+
+	req := httptest.NewRequest("DELETE", "/items", nil)
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST, PURGE" {
+		t.Errorf("Expected Allow 'GET, HEAD, OPTIONS, POST, PURGE', got '%s'", allow)
+	}
+
+	// POST is registered twice by Match + POST: no duplicates, and no HEAD without GET
+	req = httptest.NewRequest("DELETE", "/items/1", nil)
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if allow := rec.Header().Get("Allow"); allow != "OPTIONS, POST, PUT" {
+		t.Errorf("Expected Allow 'OPTIONS, POST, PUT', got '%s'", allow)
+	}
+
+	// Custom methods dispatch normally
+	req = httptest.NewRequest("PURGE", "/items", nil)
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "purged" {
+		t.Errorf("Expected body 'purged', got '%s'", rec.Body.String())
+	}
+}
+
+// TestAutoOptionsAndHead ensures OPTIONS is answered automatically and HEAD uses the GET route.
+func TestAutoOptionsAndHead(t *testing.T) {
+	app := New()
+	app.Handle("GET", "/ping", func(c *Context) {
+		c.Response.Header().Set("X-Ping", "pong")
+		c.String(http.StatusOK, "pong")
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/ping", nil)
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status code 204, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Errorf("Expected Allow 'GET, HEAD, OPTIONS', got '%s'", allow)
+	}
+
+	req = httptest.NewRequest("HEAD", "/ping", nil)
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Ping") != "pong" {
+		t.Errorf("Expected HEAD to run the GET handler, got %d", rec.Code)
+	}
+}
+
+// TestNilHandlerRejected ensures nil handlers are rejected at registration.
+func TestNilHandlerRejected(t *testing.T) {
+	app := New()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering a nil handler to panic")
+		}
+	}()
+	app.UseRoutes(NewGroup("books").GET("", nil).Routes())
+}
+
+// TestNilHandlerDispatch ensures a nil handler that slipped into the table becomes a 500 after middleware.
+func TestNilHandlerDispatch(t *testing.T) {
+	app := New()
+
+	ranMiddleware := false
+	app.Use(func(c *Context) {
+		ranMiddleware = true
+	})
+	app.routes[routeKey{method: "GET", pattern: "/broken"}] = &routeEntry{static: true}
+
+	req := httptest.NewRequest("GET", "/broken", nil)
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if !ranMiddleware {
+		t.Errorf("Expected middleware to run")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code 500, got %d", rec.Code)
+	}
+}
+
+// TestWildcardAndPrecedence ensures catch-alls work and more specific routes win.
+func TestWildcardAndPrecedence(t *testing.T) {
+	app := New()
+	app.handle("GET", "/files/*path", func(c *Context) {
+		c.String(http.StatusOK, "wildcard:"+c.Param("path"))
+	})
+	app.handle("GET", "/files/:name", func(c *Context) {
+		c.String(http.StatusOK, "param:"+c.Param("name"))
+	})
+	app.handle("GET", "/files/readme", func(c *Context) {
+		c.String(http.StatusOK, "static")
+	})
+
+	tests := map[string]string{
+		"/files/readme":  "static",
+		"/files/a.txt":   "param:a.txt",
+		"/files/a/b.txt": "wildcard:a/b.txt",
+	}
+	for path, want := range tests {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+
+		if rec.Body.String() != want {
+			t.Errorf("Path '%s': expected '%s', got '%s'", path, want, rec.Body.String())
+		}
+	}
+}
+
+// TestAppRoutes ensures routes registered on the app directly are served through the middleware.
+func TestAppRoutes(t *testing.T) {
+	app := New()
+	app.Use(func(c *Context) {
+		c.Response.Header().Set("X-Middleware", "yes")
+	})
+	app.GET("/books", func(c *Context) { c.String(http.StatusOK, "list") }).
+		POST("/books", func(c *Context) { c.String(http.StatusCreated, "created") }).
+		PATCH("/books/:id", func(c *Context) { c.String(http.StatusOK, "patched "+c.Param("id")) }).
+		DELETE("/books/:id", func(c *Context) { c.NoContent() }, WithSilentLogging())
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/books", http.StatusOK, "list"},
+		{"POST", "/books", http.StatusCreated, "created"},
+		{"PATCH", "/books/7", http.StatusOK, "patched 7"},
+		{"DELETE", "/books/7", http.StatusNoContent, ""},
+		{"PUT", "/books/7", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s %s: expected %d '%s', got %d '%s'", tt.method, tt.path, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+		if tt.code != http.StatusMethodNotAllowed && rec.Header().Get("X-Middleware") != "yes" {
+			t.Errorf("%s %s: expected the middleware to run", tt.method, tt.path)
+		}
+	}
+}
+
+// TestWildcardRemainder ensures c.Wildcard matches c.Param for single, multi and empty remainders.
+func TestWildcardRemainder(t *testing.T) {
+	app := New()
+	app.handle("GET", "/static/*path", func(c *Context) {
+		c.String(http.StatusOK, c.Wildcard()+"|"+c.Param("path"))
+	})
+	app.handle("GET", "/plain", func(c *Context) {
+		c.String(http.StatusOK, "["+c.Wildcard()+"]")
+	})
+
+	request := func(path string) string {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Body.String()
+	}
+
+	tests := map[string]string{
+		"/static/app.css": "app.css|app.css",
+		"/static/a/b":     "a/b|a/b",
+		"/static/":        "|",
+		"/static":         "|",
+		"/plain":          "[]",
+	}
+	for path, want := range tests {
+		if got := request(path); got != want {
+			t.Errorf("Path '%s': expected '%s', got '%s'", path, want, got)
+		}
+	}
+
+	app.WildcardLeadingSlash(true)
+	if got := request("/static/a/b"); got != "/a/b|a/b" {
+		t.Errorf("Expected '/a/b|a/b' with a leading slash, got '%s'", got)
+	}
+	if got := request("/static"); got != "/|" {
+		t.Errorf("Expected '/|' for an empty remainder, got '%s'", got)
+	}
+}
+
+// TestGroupConstrain ensures a group constraint applies to every route using the param.
+func TestGroupConstrain(t *testing.T) {
+	app := New()
+	app.UseRoutes(NewGroup("users").Constrain("userId", `\d+`).
+		GET("/:userId", func(c *Context) { c.String(http.StatusOK, "user "+c.Param("userId")) }).
+		GET("/:userId/orders", func(c *Context) { c.String(http.StatusOK, "orders "+c.Param("userId")) }).
+		GET("/:name/profile", func(c *Context) { c.String(http.StatusOK, "profile "+c.Param("name")) }).
+		Routes())
+	app.handle("GET", "/users/:slug", func(c *Context) { c.String(http.StatusOK, "slug "+c.Param("slug")) })
+
+	tests := map[string]string{
+		"/users/42":          "user 42",
+		"/users/42/orders":   "orders 42",
+		"/users/me":          "slug me", // falls through to the unconstrained route
+		"/users/abc/profile": "profile abc",
+	}
+	for path, want := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Body.String() != want {
+			t.Errorf("Path '%s': expected '%s', got '%s'", path, want, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/users/4x2/orders", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404 for a non-numeric userId, got %d", rec.Code)
+	}
+}
+
+// TestMixedSegments ensures several params and literals can share one segment.
+func TestMixedSegments(t *testing.T) {
+	app := New()
+	app.handle("GET", "/files/:name.:ext", func(c *Context) {
+		c.String(http.StatusOK, c.Param("name")+"|"+c.Param("ext"))
+	})
+	app.handle("GET", "/reports/:id.json", func(c *Context) {
+		c.String(http.StatusOK, "report "+c.Param("id"))
+	})
+	app.handle("GET", "/reports/:id", func(c *Context) {
+		c.String(http.StatusOK, "plain "+c.Param("id"))
+	})
+
+	tests := map[string]string{
+		"/files/photo.jpg":      "photo|jpg",
+		"/files/archive.tar.gz": "archive.tar|gz",
+		"/reports/42.json":      "report 42",
+		"/reports/42":           "plain 42",
+	}
+	for path, want := range tests {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+
+		if rec.Body.String() != want {
+			t.Errorf("Path '%s': expected '%s', got '%s'", path, want, rec.Body.String())
+		}
+	}
+
+	for _, path := range []string{"/files/noext", "/files/.hidden"} {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Path '%s': expected status code 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func benchmarkApp() *App {
+	app := New()
+	h := func(c *Context) {}
+	app.handle("GET", "/", h)
+	app.handle("GET", "/books", h)
+	app.handle("GET", "/books/:bookId", h)
+	app.handle("GET", "/users", h)
+	app.handle("GET", "/users/:userId/books/:bookId", h)
+	app.handle("GET", "/static/*filepath", h)
+	return app
+}
+
+// TestStaticRouteZeroAllocs ensures matching a static route doesn't allocate.
+func TestStaticRouteZeroAllocs(t *testing.T) {
+	app := benchmarkApp()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		app.match("GET", "http", "", "/users")
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations for a static route, got %v", allocs)
+	}
+}
+
+func BenchmarkStaticRoute(b *testing.B) {
+	app := benchmarkApp()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		app.match("GET", "http", "", "/users")
+	}
+}
+
+func BenchmarkParamRoute(b *testing.B) {
+	app := benchmarkApp()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		app.match("GET", "http", "", "/users/1/books/2")
+	}
+}
+
+// TestMethodAnyFallback ensures a dedicated handler wins and other methods reach the fallback.
+func TestMethodAnyFallback(t *testing.T) {
+	app := New()
+	group := NewGroup("")
+	group.GET("/webhook", func(c *Context) {
+		c.String(http.StatusOK, "get")
+	})
+	group.Fallback("/webhook", func(c *Context) {
+		c.String(http.StatusOK, "fallback "+c.Request.Method)
+	})
+	app.UseRoutes(group.Routes())
+
+	tests := []struct {
+		method string
+		body   string
+	}{
+		{"GET", "get"},
+		{"PUT", "fallback PUT"},
+		{"PURGE", "fallback PURGE"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest(tt.method, "/webhook", nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.body {
+			t.Errorf("%s: expected '%s', got %d '%s'", tt.method, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("TRACE", "/webhook", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected TRACE to stay blocked, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); strings.Contains(allow, "*") {
+		t.Errorf("Expected no '*' in the Allow header, got '%s'", allow)
+	}
+}
+
+// TestDefault ensures the default app recovers panics, logs them and sets a request ID.
+func TestDefault(t *testing.T) {
+	logger := &captureLogger{}
+	app := Default()
+	app.SetLogger(logger)
+	app.handle("GET", "/boom", func(c *Context) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/boom", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code 500, got %d", rec.Code)
+	}
+	if rec.Header().Get(RequestIDHeader) == "" {
+		t.Errorf("Expected a request ID header")
+	}
+	if !strings.Contains(logger.String(), "GET /boom 500") {
+		t.Errorf("Expected the 500 in the access log, got '%s'", logger.String())
+	}
+}
@@ -0,0 +1,103 @@
+package onion
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ----------------------------------------------------
+// Pagination
+// ----------------------------------------------------
+
+// PageDefaults configures Pagination.
+type PageDefaults struct {
+	PerPage    int // used when the client doesn't ask, defaults to 20
+	MaxPerPage int // upper bound for per_page/limit, defaults to 100
+}
+
+// Page is a normalized pagination request. Both styles are filled in, so
+// handlers can use whichever their storage prefers.
+type Page struct {
+	Page    int // 1-based
+	PerPage int
+	Limit   int // == PerPage
+	Offset  int // == (Page-1) * PerPage
+}
+
+// Pagination reads page/per_page or limit/offset from the query string.
+// page/per_page win when both styles are present. per_page/limit is clamped
+// to MaxPerPage; a missing or zero value uses PerPage. Negative or
+// non-numeric values, page=0 and a page so large its offset would overflow
+// return a 400 HTTPError.
+func (c *Context) Pagination(defaults PageDefaults) (Page, error) {
+	if defaults.PerPage <= 0 {
+		defaults.PerPage = 20
+	}
+	if defaults.MaxPerPage <= 0 {
+		defaults.MaxPerPage = 100
+	}
+
+	q := c.Request.URL.Query()
+	read := func(key string) (int, bool, error) {
+		raw := q.Get(key)
+		if raw == "" {
+			return 0, false, nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return 0, false, NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must be a non-negative integer", key))
+		}
+		return n, true, nil
+	}
+
+	var p Page
+	page, hasPage, err := read("page")
+	if err != nil {
+		return p, err
+	}
+	perPage, hasPerPage, err := read("per_page")
+	if err != nil {
+		return p, err
+	}
+	limit, hasLimit, err := read("limit")
+	if err != nil {
+		return p, err
+	}
+	offset, hasOffset, err := read("offset")
+	if err != nil {
+		return p, err
+	}
+
+	p.PerPage = defaults.PerPage
+	if hasPerPage && perPage > 0 {
+		p.PerPage = perPage
+	} else if !hasPerPage && hasLimit && limit > 0 {
+		p.PerPage = limit
+	}
+	if p.PerPage > defaults.MaxPerPage {
+		p.PerPage = defaults.MaxPerPage
+	}
+	p.Limit = p.PerPage
+
+	if hasPage && page == 0 {
+		return p, NewHTTPError(http.StatusBadRequest, "page must be >= 1")
+	}
+	if hasPage && page > math.MaxInt/p.PerPage {
+		// (page-1)*PerPage would overflow into a negative offset
+		return p, NewHTTPError(http.StatusBadRequest, "page is too large")
+	}
+
+	switch {
+	case hasPage:
+		p.Page = page
+		p.Offset = (page - 1) * p.PerPage
+	case hasOffset:
+		p.Offset = offset
+		p.Page = offset/p.PerPage + 1
+	default:
+		p.Page = 1
+	}
+	return p, nil
+}
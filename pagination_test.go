@@ -0,0 +1,49 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func pageFor(query string) (Page, error) {
+	c := New().newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/items?"+query, nil), nil)
+	return c.Pagination(PageDefaults{PerPage: 10, MaxPerPage: 50})
+}
+
+// TestPagination covers defaults, both styles and clamping.
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Page
+	}{
+		{"", Page{Page: 1, PerPage: 10, Limit: 10, Offset: 0}},
+		{"page=3", Page{Page: 3, PerPage: 10, Limit: 10, Offset: 20}},
+		{"page=2&per_page=25", Page{Page: 2, PerPage: 25, Limit: 25, Offset: 25}},
+		{"per_page=500", Page{Page: 1, PerPage: 50, Limit: 50, Offset: 0}},
+		{"limit=20&offset=40", Page{Page: 3, PerPage: 20, Limit: 20, Offset: 40}},
+		{"per_page=0", Page{Page: 1, PerPage: 10, Limit: 10, Offset: 0}},
+		{"page=2&limit=5&offset=99", Page{Page: 2, PerPage: 5, Limit: 5, Offset: 5}},
+	}
+
+	for _, tt := range tests {
+		got, err := pageFor(tt.query)
+		if err != nil {
+			t.Errorf("Query '%s': unexpected error %v", tt.query, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Query '%s': expected %+v, got %+v", tt.query, tt.want, got)
+		}
+	}
+}
+
+// TestPaginationInvalid ensures negative, non-numeric and overflowing values are a 400.
+func TestPaginationInvalid(t *testing.T) {
+	for _, query := range []string{"page=-1", "per_page=abc", "offset=-5", "limit=1.5", "page=0", "page=9223372036854775807", "page=461168601842738791&per_page=20"} {
+		_, err := pageFor(query)
+		if he, ok := err.(HTTPError); !ok || he.Code != http.StatusBadRequest {
+			t.Errorf("Query '%s': expected a 400 HTTPError, got %v", query, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package onion
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Param sources (params from outside the path)
+// ----------------------------------------------------
+
+// ParamSource supplies params from somewhere other than the path pattern,
+// e.g. the subdomain. They are read with c.Param like path params.
+type ParamSource interface {
+	// Params returns the params found in r, or nil.
+	Params(r *http.Request) []Param
+}
+
+// ParamSourceFunc adapts a function to ParamSource.
+type ParamSourceFunc func(r *http.Request) []Param
+
+// Params calls f(r).
+func (f ParamSourceFunc) Params(r *http.Request) []Param {
+	return f(r)
+}
+
+// AddParamSource adds params from src to every matched request.
+//
+// Precedence: path params always win, then sources in the order they were
+// added. A name already set is skipped, so c.Param and c.Params never see
+// duplicates. Source params come after path params in c.Params.
+func (a *App) AddParamSource(src ParamSource) {
+	a.paramSources = append(a.paramSources, src)
+}
+
+// sourceParams appends the params from the app's sources to params.
+func (a *App) sourceParams(r *http.Request, params []Param) []Param {
+	for _, src := range a.paramSources {
+		for _, p := range src.Params(r) {
+			if !hasParam(params, p.Key) {
+				params = append(params, p)
+			}
+		}
+	}
+	return params
+}
+
+func hasParam(params []Param, key string) bool {
+	for _, p := range params {
+		if p.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// HostParams extracts params from the request host, label by label:
+// HostParams(":tenant.example.com") gives tenant=acme for
+// "acme.example.com". Hosts that don't fit the pattern give no params.
+func HostParams(pattern string) ParamSource {
+	labels := strings.Split(strings.ToLower(pattern), ".")
+	return ParamSourceFunc(func(r *http.Request) []Param {
+		host := strings.Split(requestHost(r), ".")
+		if len(host) != len(labels) {
+			return nil
+		}
+		var params []Param
+		for i, l := range labels {
+			if strings.HasPrefix(l, ":") {
+				params = append(params, Param{l[1:], host[i]})
+			} else if l != host[i] {
+				return nil
+			}
+		}
+		return params
+	})
+}
+
+// ----------------------------------------------------
+// Form-style param decoding
+// ----------------------------------------------------
+
+// WithFormDecodedParams decodes the route's path params like form values,
+// with url.QueryUnescape: "+" becomes a space and "%2B" a plus, so
+// "/search/:query" gives "a b" for "/search/a+b". By default params are
+// path-decoded, where "+" stays a "+". It applies to requests routed
+// normally, not to c.Forward.
+func WithFormDecodedParams() RouteOption {
+	return func(r *Route) {
+		r.FormDecodedParams = true
+	}
+}
+
+// formDecodeParams re-reads params from the raw (still escaped) path and
+// query-unescapes them. Values that can't be worked out are left as they are.
+func formDecodeParams(pattern string, r *http.Request, params []Param) []Param {
+	raw, ok := matchWithParams(pattern, r.URL.EscapedPath())
+	if !ok || len(raw) != len(params) {
+		return params
+	}
+	for i, p := range raw {
+		if v, err := url.QueryUnescape(p.Value); err == nil && p.Key == params[i].Key {
+			params[i].Value = v
+		}
+	}
+	return params
+}
@@ -0,0 +1,140 @@
+package onion
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// ----------------------------------------------------
+// Body parsers (Unmarshal)
+// ----------------------------------------------------
+
+// BodyParser decodes the request body into v.
+type BodyParser func(c *Context, v interface{}) error
+
+// parsersMetaKey is the Route.Meta key for WithParser.
+const parsersMetaKey = "onion.parsers"
+
+// defaultParsers are the parsers Unmarshal falls back to.
+var defaultParsers = map[string]BodyParser{
+	"application/json":                  (*Context).bindJSON,
+	"application/xml":                   (*Context).bindXML,
+	"text/xml":                          (*Context).bindXML,
+	"application/x-www-form-urlencoded": (*Context).bindForm,
+}
+
+// RegisterParser makes Unmarshal decode bodies of contentType (a media type
+// such as "application/x-protobuf", without parameters) with parser, on
+// every route. It replaces the default for JSON, XML or form bodies; nil
+// removes a parser registered earlier.
+func (a *App) RegisterParser(contentType string, parser BodyParser) {
+	if a.parsers == nil {
+		a.parsers = make(map[string]BodyParser)
+	}
+	if parser == nil {
+		delete(a.parsers, contentType)
+		return
+	}
+	a.parsers[contentType] = parser
+}
+
+// WithParser makes Unmarshal decode bodies of contentType with parser on
+// this route, ahead of the app's parsers:
+//
+//	app.POST("/events", createEvent, onion.WithParser("application/x-protobuf", decodeProto))
+func WithParser(contentType string, parser BodyParser) RouteOption {
+	return func(r *Route) {
+		parsers, _ := r.Meta[parsersMetaKey].(map[string]BodyParser)
+		if parsers == nil {
+			parsers = make(map[string]BodyParser)
+		}
+		parsers[contentType] = parser
+		WithMeta(parsersMetaKey, parsers)(r)
+	}
+}
+
+// Unmarshal decodes the body into v with the parser for the request's
+// Content-Type, so one handler can accept several formats without branching
+// on it. Parsers are looked up on the route (WithParser), then the app
+// (RegisterParser), then the defaults: JSON, XML (by `xml` tags) and
+// urlencoded forms (by `form` tags, with the conversions of BindQuery).
+//
+// Any other type is a 415 HTTPError; decoding failures are 400 (or 413 past
+// App.MaxBodySize), like BindJSON.
+func (c *Context) Unmarshal(v interface{}) error {
+	mt, _, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "missing or invalid Content-Type")
+	}
+
+	routeParsers, _ := c.routeMeta[parsersMetaKey].(map[string]BodyParser)
+	parser := routeParsers[mt]
+	if parser == nil && c.app != nil {
+		parser = c.app.parsers[mt]
+	}
+	if parser == nil {
+		parser = defaultParsers[mt]
+	}
+	if parser == nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType, "unsupported Content-Type "+mt)
+	}
+	return parser(c, v)
+}
+
+// ParseForm parses the query and urlencoded body into c.Request.Form and
+// PostForm, like http.Request.ParseForm, enforcing App.MaxFormFields.
+// Failures are HTTPErrors: 400 for a malformed or oversized form, 413 past
+// App.MaxBodySize.
+func (c *Context) ParseForm() error {
+	if err := c.Request.ParseForm(); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return bindError(err)
+		}
+		return NewHTTPError(http.StatusBadRequest, "invalid form: "+err.Error())
+	}
+	if c.app == nil || c.app.maxFormFields <= 0 {
+		return nil
+	}
+	fields := 0
+	for _, vals := range c.Request.PostForm {
+		fields += len(vals)
+	}
+	if fields > c.app.maxFormFields {
+		return NewHTTPError(http.StatusBadRequest, fmt.Sprintf("too many form fields: more than %d", c.app.maxFormFields))
+	}
+	return nil
+}
+
+func (c *Context) bindJSON(v interface{}) error {
+	return c.BindJSON(v)
+}
+
+func (c *Context) bindXML(v interface{}) error {
+	if c.Request.Body == nil {
+		return NewHTTPError(http.StatusBadRequest, "empty request body")
+	}
+	err := xml.NewDecoder(c.Request.Body).Decode(v)
+	var maxErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &maxErr), errors.Is(err, io.EOF):
+		return bindError(err)
+	default:
+		return NewHTTPError(http.StatusBadRequest, "invalid XML: "+err.Error())
+	}
+}
+
+func (c *Context) bindForm(v interface{}) error {
+	if err := c.ParseForm(); err != nil {
+		return err
+	}
+	return bindTagged(v, "form", "form field", true, func(name string) []string {
+		return c.Request.PostForm[name]
+	})
+}
@@ -0,0 +1,61 @@
+package onion
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestUnmarshal ensures one handler decodes JSON, XML and forms, and rejects other types with 415.
+func TestUnmarshal(t *testing.T) {
+	type book struct {
+		Title string `json:"title" xml:"title" form:"title"`
+		Pages int    `json:"pages" xml:"pages" form:"pages"`
+	}
+	app := New()
+	create := func(c *Context) {
+		var b book
+		if err := c.Unmarshal(&b); err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(http.StatusCreated, b.Title+"/"+strconv.Itoa(b.Pages))
+	}
+	app.POST("/books", create)
+	app.POST("/imports", create, WithParser("text/csv", func(c *Context, v interface{}) error {
+		rec, err := csv.NewReader(c.Request.Body).Read()
+		if err != nil {
+			return NewHTTPError(http.StatusBadRequest, "invalid CSV")
+		}
+		v.(*book).Title = rec[0]
+		return nil
+	}))
+
+	tests := []struct {
+		path, contentType, body string
+		code                    int
+		want                    string
+	}{
+		{"/books", "application/json", `{"title":"Dune","pages":412}`, http.StatusCreated, "Dune/412"},
+		{"/books", "application/xml; charset=utf-8", `<book><title>Dune</title><pages>412</pages></book>`, http.StatusCreated, "Dune/412"},
+		{"/books", "application/x-www-form-urlencoded", "title=Dune&pages=412", http.StatusCreated, "Dune/412"},
+		{"/books", "application/xml", `<book><title>Dune`, http.StatusBadRequest, "invalid XML"},
+		{"/books", "text/csv", "Dune", http.StatusUnsupportedMediaType, "text/csv"},
+		{"/books", "", "Dune", http.StatusUnsupportedMediaType, "Content-Type"},
+		{"/imports", "text/csv", "Dune", http.StatusCreated, "Dune/0"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s %s: Expected %d with '%s', got %d '%s'", tt.path, tt.contentType, tt.code, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package onion
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Pattern validation (strict mode)
+// ----------------------------------------------------
+
+// StrictPatterns makes registration panic on malformed patterns instead of
+// letting them cause confusing behavior at runtime. See validatePattern for
+// the rules. It only affects routes registered after the call.
+func (a *App) StrictPatterns(on bool) {
+	a.strictPatterns = on
+}
+
+// WildcardLeadingSlash makes c.Wildcard return the remainder with a leading
+// slash ("/a/b" rather than "a/b"), for handlers that treat it as an absolute
+// path. c.Param is unaffected.
+func (a *App) WildcardLeadingSlash(on bool) {
+	a.wildcardSlash = on
+}
+
+// validatePattern checks that a pattern:
+//   - starts with "/";
+//   - has no empty segments ("/a//b", or a trailing "/" other than the root);
+//   - only uses "*" in the last segment, as "*name";
+//   - has valid, unique param names ("/a/:id/:id" is rejected).
+func validatePattern(pattern string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("onion: invalid pattern %q: must start with \"/\"", pattern)
+	}
+	if pattern == "/" {
+		return nil
+	}
+
+	segments := strings.Split(pattern[1:], "/")
+	seen := map[string]bool{}
+	addParam := func(name string, i int) error {
+		if name == "" {
+			return fmt.Errorf("onion: invalid pattern %q: empty param name in segment %d", pattern, i+1)
+		}
+		if seen[name] {
+			return fmt.Errorf("onion: invalid pattern %q: param %q declared twice", pattern, name)
+		}
+		seen[name] = true
+		return nil
+	}
+
+	for i, seg := range segments {
+		if seg == "" {
+			return fmt.Errorf("onion: invalid pattern %q: empty segment %d", pattern, i+1)
+		}
+
+		if strings.Contains(seg, "*") {
+			if i != len(segments)-1 {
+				return fmt.Errorf("onion: invalid pattern %q: \"*\" is only allowed in the last segment", pattern)
+			}
+			if !strings.HasPrefix(seg, "*") || !isParamName(seg[1:]) {
+				return fmt.Errorf("onion: invalid pattern %q: wildcard must look like \"*name\"", pattern)
+			}
+			if err := addParam(seg[1:], i); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.Contains(seg, ":") {
+			parts := parseSegment(seg)
+			for j, p := range parts {
+				if p.literal != "" {
+					continue
+				}
+				if err := addParam(p.param, i); err != nil {
+					return err
+				}
+				if j+1 < len(parts) && parts[j+1].literal == "" {
+					return fmt.Errorf("onion: invalid pattern %q: params in segment %d must be separated by a literal", pattern, i+1)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ----------------------------------------------------
+// Route table validation
+// ----------------------------------------------------
+
+// Validate checks the whole route table and reports every problem at once:
+// routes registered twice (the later one silently wins), malformed patterns,
+// nil handlers, and ambiguous routes, i.e. patterns on the same method and
+// host that differ only in param names ("/users/:id" vs "/users/:name"),
+// where only one can ever match. Routes with constraints (WithConstraint,
+// RouteGroup.Constrain) aren't ambiguous, as they only take some values.
+// Call it before Run, or in a test.
+func (a *App) Validate() error {
+	a.routesMu.RLock()
+	defer a.routesMu.RUnlock()
+
+	var errs []error
+	for _, key := range a.duplicates {
+		errs = append(errs, fmt.Errorf("onion: %s registered more than once", key))
+	}
+
+	keys := make([]routeKey, 0, len(a.routes))
+	for key := range a.routes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	shapes := make(map[routeKey]routeKey)
+	for _, key := range keys {
+		if err := validatePattern(key.pattern); err != nil {
+			errs = append(errs, err)
+		}
+		if a.routes[key].handler == nil {
+			errs = append(errs, fmt.Errorf("onion: nil handler for %s", key))
+		}
+
+		if len(a.routes[key].constraints) > 0 {
+			continue
+		}
+		shape := key
+		shape.pattern = patternShape(key.pattern)
+		if other, ok := shapes[shape]; ok {
+			errs = append(errs, fmt.Errorf("onion: %s is ambiguous with %s", key, other))
+			continue
+		}
+		shapes[shape] = key
+	}
+	return errors.Join(errs...)
+}
+
+// patternShape drops param names from a pattern: "/users/:id/*rest" becomes
+// "/users/:/*". Two patterns with the same shape match the same paths.
+func patternShape(pattern string) string {
+	if !strings.ContainsAny(pattern, ":*") {
+		return pattern
+	}
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		switch {
+		case strings.HasPrefix(seg, "*"):
+			segments[i] = "*"
+		case strings.Contains(seg, ":"):
+			var b strings.Builder
+			for _, p := range parseSegment(seg) {
+				if p.param != "" {
+					b.WriteString(":")
+				} else {
+					b.WriteString(p.literal)
+				}
+			}
+			segments[i] = b.String()
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,81 @@
+package onion
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestStrictPatterns covers each invalid pattern shape and a valid one.
+func TestStrictPatterns(t *testing.T) {
+	invalid := map[string]string{
+		"books":             `must start with "/"`,
+		"/books//1":         "empty segment 2",
+		"/books/":           "empty segment 2",
+		"/files/*path/edit": `"*" is only allowed in the last segment`,
+		"/files/x*":         `wildcard must look like "*name"`,
+		"/a/:id/:id":        `param "id" declared twice`,
+		"/a/:id/*id":        `param "id" declared twice`,
+		"/a/:":              "empty param name in segment 2",
+		"/f/:name:ext":      "must be separated by a literal",
+	}
+
+	for pattern, want := range invalid {
+		func() {
+			app := New()
+			app.StrictPatterns(true)
+
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, want) {
+					t.Errorf("Pattern '%s': expected panic containing '%s', got '%s'", pattern, want, msg)
+				}
+			}()
+			app.handle("GET", pattern, func(c *Context) {})
+		}()
+	}
+
+	app := New()
+	app.StrictPatterns(true)
+	app.handle("GET", "/", func(c *Context) {})
+	app.handle("GET", "/books/:bookId/files/:name.:ext", func(c *Context) {})
+	app.handle("GET", "/static/*filepath", func(c *Context) {})
+}
+
+// TestPatternsNotStrict ensures patterns are accepted as before by default.
+func TestPatternsNotStrict(t *testing.T) {
+	app := New()
+	app.handle("GET", "/books/", func(c *Context) {})
+	app.handle("GET", "/a/:id/:id", func(c *Context) {})
+}
+
+// TestValidate ensures distinct route table problems are reported together.
+func TestValidate(t *testing.T) {
+	app := New()
+	ok := func(c *Context) {}
+	app.handle("GET", "/books", ok)
+	app.handle("GET", "/books/:id", ok)
+	app.handle("GET", "/books/new", ok)
+	app.handle("POST", "/books/:bookId", ok) // other method, not ambiguous
+
+	if err := app.Validate(); err != nil {
+		t.Fatalf("Expected a valid table, got %v", err)
+	}
+
+	app.handle("GET", "/books", ok)
+	app.handle("GET", "/books/:bookId", ok)
+	app.handle("GET", "/authors//:id", ok)
+
+	err := app.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{
+		"GET /books registered more than once",
+		"GET /books/:id is ambiguous with GET /books/:bookId",
+		`invalid pattern "/authors//:id": empty segment 2`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected '%s' in the errors, got '%v'", want, err)
+		}
+	}
+}
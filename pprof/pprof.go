@@ -0,0 +1,50 @@
+// Package pprof serves the net/http/pprof profiles from an onion app.
+//
+// It lives apart from package onion because importing net/http/pprof
+// registers its handlers on http.DefaultServeMux; only programs that import
+// this package get them.
+package pprof
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"onion"
+)
+
+// Register adds the net/http/pprof handlers to app under prefix, e.g.
+// pprof.Register(app, "/debug/pprof") serves the index at /debug/pprof/ and
+// profiles at /debug/pprof/heap, /debug/pprof/profile?seconds=10, ...
+//
+// The routes go through the normal dispatch, so middleware registered with
+// Use (auth, IP filters) runs first: don't expose profiles publicly. As with
+// any import of net/http/pprof, the same handlers are also registered on
+// http.DefaultServeMux; don't serve that mux publicly.
+func Register(app *onion.App, prefix string) {
+	prefix = strings.TrimRight(prefix, "/")
+	app.GET(prefix+"/*name", func(c *onion.Context) {
+		switch name := c.Param("name"); name {
+		case "":
+			if !strings.HasSuffix(c.Request.URL.Path, "/") {
+				// The index links are relative to the trailing slash
+				http.Redirect(c.Response, c.Request, prefix+"/", http.StatusMovedPermanently)
+				return
+			}
+			pprof.Index(c.Response, c.Request)
+		case "cmdline":
+			pprof.Cmdline(c.Response, c.Request)
+		case "profile":
+			pprof.Profile(c.Response, c.Request)
+		case "symbol":
+			pprof.Symbol(c.Response, c.Request)
+		case "trace":
+			pprof.Trace(c.Response, c.Request)
+		default:
+			pprof.Handler(name).ServeHTTP(c.Response, c.Request)
+		}
+	})
+	app.POST(prefix+"/symbol", func(c *onion.Context) {
+		pprof.Symbol(c.Response, c.Request)
+	})
+}
@@ -0,0 +1,48 @@
+package pprof
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"onion"
+)
+
+// TestRegister ensures the pprof routes answer on the app once registered.
+func TestRegister(t *testing.T) {
+	app := onion.New()
+	app.SetLogger(discard{})
+	Register(app, "/debug/pprof")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.RunListener(ln)
+	defer app.Shutdown(context.Background())
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	for path, code := range map[string]int{
+		"/debug/pprof/":                  http.StatusOK,
+		"/debug/pprof":                   http.StatusMovedPermanently,
+		"/debug/pprof/goroutine?debug=1": http.StatusOK,
+		"/debug/pprof/cmdline":           http.StatusOK,
+		"/other":                         http.StatusNotFound,
+	} {
+		resp, err := client.Get("http://" + ln.Addr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != code {
+			t.Errorf("%s: expected status code %d, got %d", path, code, resp.StatusCode)
+		}
+	}
+}
+
+type discard struct{}
+
+func (discard) Printf(string, ...interface{}) {}
@@ -0,0 +1,57 @@
+package onion
+
+import "fmt"
+
+// ----------------------------------------------------
+// Per-request resources (Provide)
+// ----------------------------------------------------
+
+// Provide creates a per-request resource (a DB transaction, a client, ...)
+// before the handler runs and stores it with c.Set(key, ...). The cleanup
+// function returned by the factory runs once the rest of the chain is done,
+// even if it panicked.
+//
+// Cleanup decides between commit and rollback by looking at c.Errors(): a
+// panic is recorded there before cleanup runs (and then re-panics), as is any
+// error passed to c.Error. For example:
+//
+//	app.Use(onion.Provide("tx", func(c *onion.Context) (interface{}, func(), error) {
+//		tx, err := db.Begin()
+//		if err != nil {
+//			return nil, nil, err
+//		}
+//		return tx, func() {
+//			if len(c.Errors()) > 0 {
+//				tx.Rollback()
+//			} else {
+//				tx.Commit()
+//			}
+//		}, nil
+//	}))
+//
+// If the factory fails, its error goes to the error handler and the chain is aborted.
+func Provide(key string, factory func(c *Context) (interface{}, func(), error)) HandlerFunc {
+	return func(c *Context) {
+		value, cleanup, err := factory(c)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Set(key, value)
+
+		if cleanup == nil {
+			return
+		}
+
+		defer func() {
+			if rec := recover(); rec != nil {
+				c.errors = append(c.errors, fmt.Errorf("panic: %v", rec))
+				cleanup()
+				panic(rec)
+			}
+			cleanup()
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,90 @@
+package onion
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeTx struct {
+	committed  bool
+	rolledBack bool
+}
+
+func txProvider(tx *fakeTx) HandlerFunc {
+	return Provide("tx", func(c *Context) (interface{}, func(), error) {
+		return tx, func() {
+			if len(c.Errors()) > 0 {
+				tx.rolledBack = true
+			} else {
+				tx.committed = true
+			}
+		}, nil
+	})
+}
+
+// TestProvideCommit ensures the resource is available to the handler and committed on success.
+func TestProvideCommit(t *testing.T) {
+	tx := &fakeTx{}
+	app := New()
+	app.Use(txProvider(tx))
+	app.handle("GET", "/", func(c *Context) {
+		v, ok := c.Get("tx")
+		if !ok || v.(*fakeTx) != tx {
+			t.Errorf("Expected the handler to see the provided tx")
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !tx.committed || tx.rolledBack {
+		t.Errorf("Expected commit, got committed=%v rolledBack=%v", tx.committed, tx.rolledBack)
+	}
+}
+
+// TestProvidePanic ensures a panic triggers the cleanup with an error recorded.
+func TestProvidePanic(t *testing.T) {
+	tx := &fakeTx{}
+	app := New()
+	app.Use(txProvider(tx))
+	app.handle("GET", "/", func(c *Context) {
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected the panic to propagate after cleanup")
+			}
+		}()
+		app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	if !tx.rolledBack || tx.committed {
+		t.Errorf("Expected rollback, got committed=%v rolledBack=%v", tx.committed, tx.rolledBack)
+	}
+}
+
+// TestProvideFactoryError ensures a failing factory aborts with the error handler.
+func TestProvideFactoryError(t *testing.T) {
+	app := New()
+	app.Use(Provide("tx", func(c *Context) (interface{}, func(), error) {
+		return nil, nil, errors.New("db down")
+	}))
+	called := false
+	app.handle("GET", "/", func(c *Context) {
+		called = true
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code 500, got %d", rec.Code)
+	}
+	if called {
+		t.Errorf("Expected handler not to run")
+	}
+}
@@ -0,0 +1,68 @@
+package onion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// ----------------------------------------------------
+// Proxying a request upstream
+// ----------------------------------------------------
+
+// ProxyPass forwards the request to targetURL and relays the upstream
+// response: status, headers and body. The method, body and headers are kept
+// (minus hop-by-hop ones), X-Forwarded-For, -Host and -Proto are set, and the
+// request's query is kept unless targetURL has its own. The upstream is
+// computed by the handler, e.g. per tenant:
+//
+//	app.GET("/reports/*path", func(c *onion.Context) {
+//		if err := c.ProxyPass(tenantURL(c) + "/reports/" + c.Param("path")); err != nil {
+//			c.Error(err)
+//		}
+//	})
+//
+// The call goes through Context.HTTPClient's transport, so it ends with the
+// incoming request. A failed upstream call is a 502 HTTPError (504 if the
+// request's deadline passed) and nothing is written, so the handler can
+// answer instead. The error's message is generic, since it reaches the
+// client; the upstream URL and cause are logged.
+func (c *Context) ProxyPass(targetURL string) error {
+	target, err := url.Parse(targetURL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		c.app.logger.Printf("onion: %s %s: invalid upstream URL %q", c.Request.Method, c.Request.URL.Path, targetURL)
+		return NewHTTPError(http.StatusBadGateway)
+	}
+
+	var proxyErr error
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out.URL.Scheme = target.Scheme
+			r.Out.URL.Host = target.Host
+			r.Out.URL.Path, r.Out.URL.RawPath = target.Path, target.RawPath
+			if target.RawQuery != "" {
+				r.Out.URL.RawQuery = target.RawQuery
+			}
+			r.Out.Host = ""
+			r.SetXForwarded()
+		},
+		Transport: c.HTTPClient().Transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			proxyErr = err
+		},
+	}
+	proxy.ServeHTTP(c.Response, c.Request)
+
+	if proxyErr == nil {
+		return nil
+	}
+	c.app.logger.Printf("onion: %s %s: proxying to %s: %v", c.Request.Method, c.Request.URL.Path, target.Redacted(), proxyErr)
+	switch {
+	case errors.Is(proxyErr, context.DeadlineExceeded):
+		return NewHTTPError(http.StatusGatewayTimeout, "upstream timed out")
+	default:
+		return NewHTTPError(http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,62 @@
+package onion
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestProxyPass ensures the request is forwarded as is and the upstream response relayed.
+func TestProxyPass(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", "tenant-a")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+string(body)+" "+
+			r.Header.Get("X-Api-Key")+" "+r.Header.Get("X-Forwarded-Host"))
+	}))
+	defer upstream.Close()
+
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.handle("POST", "/t/:tenant/*path", func(c *Context) {
+		if err := c.ProxyPass(upstream.URL + "/" + c.Param("path")); err != nil {
+			c.Error(err)
+		}
+	})
+	app.handle("GET", "/down", func(c *Context) {
+		if err := c.ProxyPass("http://127.0.0.1:1/"); err != nil {
+			c.Error(err)
+		}
+	})
+
+	req := httptest.NewRequest("POST", "http://api.example.com/t/a/books?page=2", strings.NewReader(`{"title":"Dune"}`))
+	req.Header.Set("X-Api-Key", "k1")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected status code 201, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Upstream") != "tenant-a" {
+		t.Errorf("Expected the upstream headers, got %v", rec.Header())
+	}
+	want := `POST /books?page=2 {"title":"Dune"} k1 api.example.com`
+	if rec.Body.String() != want {
+		t.Errorf("Expected '%s', got '%s'", want, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/down", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected status code 502 for an unreachable upstream, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "127.0.0.1") {
+		t.Errorf("Expected the upstream to stay out of the response, got '%s'", rec.Body.String())
+	}
+	if !strings.Contains(logger.String(), "proxying to http://127.0.0.1:1/") {
+		t.Errorf("Expected the upstream failure in the log, got '%s'", logger.String())
+	}
+}
@@ -0,0 +1,136 @@
+package onion
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------
+// Rate limiting
+// ----------------------------------------------------
+
+// RateLimitConfig configures RateLimit and RateLimitBy.
+type RateLimitConfig struct {
+	// Limit is how many requests a client may make per Window. Bursts up to
+	// Limit are allowed; the quota then refills steadily over the window.
+	Limit int
+
+	// Window is the period Limit applies to. Defaults to a minute.
+	Window time.Duration
+}
+
+// RateLimit limits each client IP (see Context.ClientIP) to config.Limit
+// requests per config.Window. Requests over the limit get a 429 HTTPError
+// with Retry-After; all responses carry X-RateLimit-Limit and
+// X-RateLimit-Remaining.
+func RateLimit(config RateLimitConfig) HandlerFunc {
+	return RateLimitBy(nil, config)
+}
+
+// RateLimitBy is RateLimit with buckets keyed by keyFn, e.g. the
+// authenticated user set by an auth middleware, so users behind one NAT
+// don't share a quota:
+//
+//	api.Use(auth)
+//	api.Use(onion.RateLimitBy(func(c *onion.Context) string {
+//		user, _ := c.Get("user")
+//		name, _ := user.(string)
+//		return name
+//	}, onion.RateLimitConfig{Limit: 100, Window: time.Minute}))
+//
+// Requests for which keyFn returns "" (anonymous ones) fall back to the
+// client IP. Register it after the middleware that sets the key. For
+// different quotas (free and paid plans, say), use one RateLimitBy per
+// group of routes or users.
+func RateLimitBy(keyFn func(*Context) string, config RateLimitConfig) HandlerFunc {
+	if config.Limit <= 0 {
+		panic(fmt.Sprintf("onion: invalid rate limit %d", config.Limit))
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	limiter := &rateLimiter{
+		limit:   float64(config.Limit),
+		rate:    float64(config.Limit) / config.Window.Seconds(),
+		window:  config.Window,
+		buckets: make(map[string]*rateBucket),
+	}
+	limitHeader := strconv.Itoa(config.Limit)
+
+	return func(c *Context) {
+		key := ""
+		if keyFn != nil {
+			key = keyFn(c)
+		}
+		if key != "" {
+			key = "key:" + key
+		} else {
+			key = "ip:" + c.ClientIP()
+		}
+
+		remaining, wait := limiter.take(key, time.Now())
+		h := c.Response.Header()
+		h.Set("X-RateLimit-Limit", limitHeader)
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if wait > 0 {
+			h.Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.Error(NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded"))
+			c.Abort()
+		}
+	}
+}
+
+// rateLimiter is a set of token buckets, one per key.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     float64 // bucket size
+	rate      float64 // tokens per second
+	window    time.Duration
+	buckets   map[string]*rateBucket
+	lastSweep time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take spends a token from key's bucket. It returns the tokens left, or how
+// long until one is available if the bucket is empty.
+func (l *rateLimiter) take(key string, now time.Time) (remaining int, wait time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: l.limit, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.limit, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return 0, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return int(b.tokens), 0
+}
+
+// sweep drops the buckets idle for a whole window, which are full again and
+// so no different from new ones. It runs at most once per window.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestRateLimitBy ensures each principal has its own bucket, and anonymous requests fall back to the IP.
+func TestRateLimitBy(t *testing.T) {
+	app := New()
+	app.Use(func(c *Context) {
+		if user := c.Request.Header.Get("X-User"); user != "" {
+			c.Set("user", user)
+		}
+	})
+	app.Use(RateLimitBy(func(c *Context) string {
+		user, _ := c.Get("user")
+		name, _ := user.(string)
+		return name
+	}, RateLimitConfig{Limit: 2, Window: time.Hour}))
+	app.GET("/books", func(c *Context) {
+		c.String(http.StatusOK, "books")
+	})
+
+	send := func(user, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/books", nil)
+		req.RemoteAddr = ip + ":1234"
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		user, ip  string
+		code      int
+		remaining string
+	}{
+		{"ann", "10.0.0.1", http.StatusOK, "1"},
+		{"ann", "10.0.0.2", http.StatusOK, "0"},
+		{"ann", "10.0.0.3", http.StatusTooManyRequests, "0"},
+		{"bob", "10.0.0.1", http.StatusOK, "1"},
+		{"bob", "10.0.0.1", http.StatusOK, "0"},
+		{"", "10.0.0.1", http.StatusOK, "1"},
+		{"", "10.0.0.1", http.StatusOK, "0"},
+		{"", "10.0.0.1", http.StatusTooManyRequests, "0"},
+		{"", "10.0.0.9", http.StatusOK, "1"},
+	}
+	for i, tt := range tests {
+		rec := send(tt.user, tt.ip)
+		if rec.Code != tt.code || rec.Header().Get("X-RateLimit-Remaining") != tt.remaining {
+			t.Errorf("Request %d (%s from %s): Expected %d with %s remaining, got %d with %s",
+				i, tt.user, tt.ip, tt.code, tt.remaining, rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+		}
+		if tt.code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "1800" {
+			t.Errorf("Request %d: Expected Retry-After 1800, got '%s'", i, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+// TestRateLimiterRefill ensures buckets refill over the window.
+func TestRateLimiterRefill(t *testing.T) {
+	l := &rateLimiter{limit: 2, rate: 2.0 / 60, window: time.Minute, buckets: map[string]*rateBucket{}}
+	now := time.Now()
+	l.take("a", now)
+	l.take("a", now)
+	if _, wait := l.take("a", now); wait != 30*time.Second {
+		t.Errorf("Expected to wait 30s for a token, got %v", wait)
+	}
+	if remaining, wait := l.take("a", now.Add(30*time.Second)); wait != 0 || remaining != 0 {
+		t.Errorf("Expected a token after 30s, got %d remaining (wait %v)", remaining, wait)
+	}
+	l.take("b", now)
+	l.take("c", now.Add(2*time.Minute))
+	if _, ok := l.buckets["b"]; ok {
+		t.Error("Expected idle buckets to be swept")
+	}
+}
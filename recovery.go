@@ -0,0 +1,107 @@
+package onion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+)
+
+// ----------------------------------------------------
+// Panic recovery
+// ----------------------------------------------------
+
+// Recovery turns panics in later middlewares and handlers into responses
+// instead of dropped connections. Register it first so it covers the rest.
+//
+// A panic with an HTTPError (or *HTTPError, or an error wrapping one) is a
+// deliberate shortcut out of a deep call stack: it goes to the error handler
+// with its own status and message, and nothing is logged:
+//
+//	panic(onion.NewHTTPError(http.StatusNotFound, "book not found"))
+//
+// Any other panic is a bug: it's logged with its stack trace and answered
+// with a 500. http.ErrAbortHandler is re-panicked so net/http can abort the
+// connection as usual. Panic types registered with App.MapPanic get their
+// own status instead of a 500.
+func Recovery() HandlerFunc {
+	return func(c *Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			c.Abort()
+
+			if he, ok := panicHTTPError(rec); ok {
+				c.Error(he)
+				return
+			}
+			if status, ok := c.app.panicStatus[reflect.TypeOf(rec)]; ok {
+				// Like c.Error, don't append to a response already on its way
+				if !c.ResponseWritten() {
+					c.JSON(status, rec)
+				}
+				return
+			}
+			c.app.logger.Printf("onion: panic serving %s %s: %v%s\n%s",
+				c.Request.Method, c.Request.URL.Path, rec, formatFields(c.fields), debug.Stack())
+			c.Error(fmt.Errorf("panic: %v", rec))
+		}()
+		c.Next()
+	}
+}
+
+// MapPanic makes Recovery answer panics with values of type t with status
+// and the value itself as the JSON body, without logging them, e.g. for a
+// domain error panicked from deep in a call stack:
+//
+//	type ValidationError struct {
+//		Field  string `json:"field"`
+//		Reason string `json:"reason"`
+//	}
+//
+//	app.MapPanic(reflect.TypeOf(ValidationError{}), http.StatusUnprocessableEntity)
+//
+// The type must match exactly: ValidationError and *ValidationError are
+// mapped separately. Panics of other types are still a 500. If the response
+// was already started, nothing more is written. It panics on a status
+// outside 100-599.
+func (a *App) MapPanic(t reflect.Type, status int) {
+	if t == nil {
+		panic("onion: MapPanic with a nil type")
+	}
+	if status < 100 || status > 599 {
+		panic(fmt.Sprintf("onion: invalid MapPanic status %d for %s", status, t))
+	}
+	if a.panicStatus == nil {
+		a.panicStatus = make(map[reflect.Type]int)
+	}
+	a.panicStatus[t] = status
+}
+
+// panicHTTPError extracts an HTTPError from a recovered panic value.
+func panicHTTPError(rec interface{}) (HTTPError, bool) {
+	switch v := rec.(type) {
+	case HTTPError:
+		return v, true
+	case *HTTPError:
+		if v != nil {
+			return *v, true
+		}
+	case error:
+		var he HTTPError
+		if errors.As(v, &he) {
+			return he, true
+		}
+		var hep *HTTPError
+		if errors.As(v, &hep) && hep != nil {
+			return *hep, true
+		}
+	}
+	return HTTPError{}, false
+}
@@ -0,0 +1,129 @@
+package onion
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRecoveryHTTPError ensures a panic with an HTTPError keeps its status and message.
+func TestRecoveryHTTPError(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.Use(Recovery())
+	app.handle("GET", "/books/:id", func(c *Context) {
+		panic(NewHTTPError(http.StatusNotFound, "book not found"))
+	})
+	app.handle("GET", "/wrapped", func(c *Context) {
+		panic(fmt.Errorf("loading: %w", NewHTTPError(http.StatusForbidden)))
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/books/1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "book not found") {
+		t.Errorf("Expected the panic message in the body, got '%s'", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/wrapped", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status code 403, got %d", rec.Code)
+	}
+
+	if logs.String() != "" {
+		t.Errorf("Expected typed panics not to be logged, got '%s'", logs.String())
+	}
+}
+
+// TestRecoveryBug ensures other panics become a 500 and are logged with a stack trace.
+func TestRecoveryBug(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.Use(Recovery())
+	app.handle("GET", "/", func(c *Context) {
+		var m map[string]int
+		m["boom"] = 1
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code 500, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "nil map") {
+		t.Errorf("Expected the panic not to leak to the client, got '%s'", rec.Body.String())
+	}
+	if !strings.Contains(logs.String(), "panic serving GET /") || !strings.Contains(logs.String(), "goroutine") {
+		t.Errorf("Expected the panic and stack trace to be logged, got '%s'", logs.String())
+	}
+}
+
+type shelfFullError struct {
+	Shelf    string `json:"shelf"`
+	Capacity int    `json:"capacity"`
+}
+
+// TestMapPanic ensures registered panic types get their status and a JSON body, others a 500.
+func TestMapPanic(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.MapPanic(reflect.TypeOf(shelfFullError{}), http.StatusUnprocessableEntity)
+	app.Use(Recovery())
+	app.handle("POST", "/shelves/:name", func(c *Context) {
+		panic(shelfFullError{Shelf: c.Param("name"), Capacity: 20})
+	})
+	app.handle("POST", "/other", func(c *Context) {
+		panic(&shelfFullError{Shelf: "other"})
+	})
+	app.handle("POST", "/partial", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic(shelfFullError{Shelf: "partial"})
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("POST", "/shelves/fiction", nil))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code 422, got %d", rec.Code)
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"shelf":"fiction","capacity":20}` {
+		t.Errorf("Expected the panic value as JSON, got '%s'", body)
+	}
+	if logs.String() != "" {
+		t.Errorf("Expected mapped panics not to be logged, got '%s'", logs.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("POST", "/other", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code 500 for an unmapped type, got %d", rec.Code)
+	}
+	if !strings.Contains(logs.String(), "panic serving POST /other") {
+		t.Errorf("Expected the unmapped panic to be logged, got '%s'", logs.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("POST", "/partial", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Errorf("Expected nothing appended after a partial write, got %d '%s'", rec.Code, rec.Body.String())
+	}
+
+	for _, status := range []int{99, 600, 999} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected MapPanic with status %d to panic", status)
+				}
+			}()
+			app.MapPanic(reflect.TypeOf(0), status)
+		}()
+	}
+}
@@ -0,0 +1,119 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// TestReload ensures the route table is swapped as a whole.
+func TestReload(t *testing.T) {
+	app := New()
+	app.UseRoutes(NewGroup("v1").GET("", func(c *Context) { c.String(http.StatusOK, "v1") }).Routes())
+
+	app.Reload(NewGroup("v2").GET("", func(c *Context) { c.String(http.StatusOK, "v2") }).Routes())
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/v1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected old routes to be gone, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/v2", nil))
+	if rec.Body.String() != "v2" {
+		t.Errorf("Expected 'v2', got '%s'", rec.Body.String())
+	}
+}
+
+// TestReloadConcurrent reloads routes while requests are in flight (run with -race).
+func TestReloadConcurrent(t *testing.T) {
+	app := New()
+	routesA := NewGroup("x").GET("", func(c *Context) { c.String(http.StatusOK, "a") }).Routes()
+	routesB := NewGroup("x").GET("", func(c *Context) { c.String(http.StatusOK, "b") }).Routes()
+	app.UseRoutes(routesA)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				rec := httptest.NewRecorder()
+				app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/x", nil))
+				if body := rec.Body.String(); body != "a" && body != "b" {
+					t.Errorf("Expected 'a' or 'b', got '%s'", body)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		if i%2 == 0 {
+			app.Reload(routesB)
+		} else {
+			app.Reload(routesA)
+		}
+	}
+	wg.Wait()
+}
+
+// TestAddRouteConcurrent registers routes while requests are in flight and
+// the route table is listed (run with -race).
+func TestAddRouteConcurrent(t *testing.T) {
+	app := New()
+	app.SetLogger(&captureLogger{})
+	app.handle("GET", "/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+
+	var wg sync.WaitGroup
+	listing, stop := make(chan struct{}), make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		app.printBanner("127.0.0.1:3333")
+		close(listing)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				app.printBanner("127.0.0.1:3333")
+				app.debugInfo()
+			}
+		}
+	}()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				rec := httptest.NewRecorder()
+				app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/ping", nil))
+				if rec.Body.String() != "pong" {
+					t.Errorf("Expected 'pong', got '%s'", rec.Body.String())
+					return
+				}
+			}
+		}()
+	}
+
+	<-listing
+	for i := 0; i < 100; i++ {
+		app.AddRoute(Route{
+			Method:  http.MethodGet,
+			Pattern: "/dynamic/" + strconv.Itoa(i),
+			Handler: func(c *Context) { c.String(http.StatusOK, "dynamic") },
+		})
+	}
+	close(stop)
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/dynamic/99", nil))
+	if rec.Body.String() != "dynamic" {
+		t.Errorf("Expected 'dynamic', got '%s'", rec.Body.String())
+	}
+}
@@ -0,0 +1,74 @@
+package onion
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ----------------------------------------------------
+// Status remapping (legacy clients)
+// ----------------------------------------------------
+
+// RemapStatus rewrites response statuses on their way out, for legacy
+// clients that choke on some of them, e.g. map[int]int{422: 400, 429: 503}.
+// The status is translated once, when the handler (or the error handler)
+// sends it, so the access log and stats see what went on the wire.
+//
+// Use it sparingly and on the routes of those clients only (a group or a
+// route middleware): the remapped status loses meaning (a 400 no longer says
+// the body was well-formed), caches and retry logic act on the new status,
+// and the body still describes the original one. Mapping from or to 204 or
+// 304 is allowed but changes whether a body is permitted, so a handler that
+// writes one may fail or have it dropped.
+func RemapStatus(mapping map[int]int) HandlerFunc {
+	for from, to := range mapping {
+		if from < 100 || from > 999 || to < 200 || to > 999 {
+			panic(fmt.Sprintf("onion: invalid RemapStatus mapping %d -> %d", from, to))
+		}
+	}
+	return func(c *Context) {
+		orig := c.Response
+		c.Response = &remapWriter{ResponseWriter: orig, mapping: mapping}
+		defer func() { c.Response = orig }()
+		c.Next()
+	}
+}
+
+// remapWriter translates the status of the response, see RemapStatus.
+type remapWriter struct {
+	http.ResponseWriter
+	mapping map[int]int
+	sent    bool
+}
+
+func (w *remapWriter) WriteHeader(code int) {
+	if !w.sent && code >= 200 {
+		w.sent = true
+		if to, ok := w.mapping[code]; ok {
+			code = to
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *remapWriter) Write(b []byte) (int, error) {
+	if !w.sent {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, committing the remapped status first.
+func (w *remapWriter) Flush() {
+	if !w.sent {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (w *remapWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,47 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRemapStatus ensures mapped statuses are translated on the wire and others are kept.
+func TestRemapStatus(t *testing.T) {
+	logs := &captureLogger{}
+	app := New()
+	app.SetLogger(logs)
+	app.Use(Logger(LoggerConfig{}))
+	app.Use(RemapStatus(map[int]int{http.StatusUnprocessableEntity: http.StatusBadRequest, http.StatusOK: http.StatusAccepted}))
+	app.POST("/books", func(c *Context) {
+		c.Error(NewHTTPError(http.StatusUnprocessableEntity, "title is required"))
+	})
+	app.POST("/notes", func(c *Context) {
+		c.Response.WriteHeader(http.StatusCreated)
+		c.Response.WriteHeader(http.StatusUnprocessableEntity)
+	})
+	app.POST("/drafts", func(c *Context) {
+		c.Response.Write([]byte("saved"))
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/books", http.StatusBadRequest, "title is required"},
+		{"/notes", http.StatusCreated, ""},
+		{"/drafts", http.StatusAccepted, "saved"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("POST", tt.path, nil))
+		if rec.Code != tt.code || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s: Expected %d '%s', got %d '%s'", tt.path, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+	if !strings.Contains(logs.String(), "POST /books 400") {
+		t.Errorf("Expected the remapped status in the access log, got '%s'", logs.String())
+	}
+}
@@ -0,0 +1,39 @@
+package onion
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// ----------------------------------------------------
+// Request ID
+// ----------------------------------------------------
+
+// RequestIDHeader is read from incoming requests and set on responses.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID makes sure every request has an ID: the client's X-Request-ID is
+// reused if present, otherwise a random one is generated. The ID is echoed in
+// the response header and attached as the "request_id" log field.
+func RequestID() HandlerFunc {
+	return func(c *Context) {
+		id := c.Request.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		c.requestID = id
+		c.Response.Header().Set(RequestIDHeader, id)
+		c.WithField("request_id", id)
+	}
+}
+
+// RequestID returns the ID assigned by the RequestID middleware, or "".
+func (c *Context) RequestID() string {
+	return c.requestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
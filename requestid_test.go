@@ -0,0 +1,33 @@
+package onion
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRequestID ensures an incoming ID is reused and a missing one is generated.
+func TestRequestID(t *testing.T) {
+	app := New()
+	app.Use(RequestID())
+
+	var seen string
+	app.handle("GET", "/", func(c *Context) {
+		seen = c.RequestID()
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if seen != "abc" || rec.Header().Get("X-Request-ID") != "abc" {
+		t.Errorf("Expected request ID 'abc', got '%s' / '%s'", seen, rec.Header().Get("X-Request-ID"))
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if len(seen) != 32 || rec.Header().Get("X-Request-ID") != seen {
+		t.Errorf("Expected a generated 32-char request ID, got '%s'", seen)
+	}
+}
@@ -0,0 +1,91 @@
+package onion
+
+import "net/http"
+
+// ----------------------------------------------------
+// Typed results (HandleErr)
+// ----------------------------------------------------
+
+// Result is a response body with its status, returned from a HandleErr handler.
+type Result struct {
+	Status int
+	Body   interface{}
+}
+
+// Created is a 201 Result, the usual answer to a create.
+func Created(body interface{}) Result {
+	return Result{Status: http.StatusCreated, Body: body}
+}
+
+// HandleErr adapts a handler that returns its response instead of writing it:
+//
+//	app.POST("/books", onion.HandleErr(func(c *onion.Context) (interface{}, error) {
+//		var book Book
+//		if err := c.BindJSON(&book); err != nil {
+//			return nil, err
+//		}
+//		return onion.Created(books.Add(book)), nil
+//	}))
+//
+// The status follows from what fn returns:
+//
+//   - an error (or an HTTPError value) goes to the error handler, as c.Error
+//   - a Result (or *Result) is rendered as JSON with its status, 200 if unset;
+//     a nil Body is sent without one
+//   - nil is 204 No Content
+//   - any other value is rendered as JSON with 200
+//
+// If fn already wrote a response, a returned value is ignored, and an error
+// is only recorded (see Context.Errors), so logging and cleanups see it.
+func HandleErr(fn func(c *Context) (interface{}, error)) HandlerFunc {
+	return func(c *Context) {
+		v, err := fn(c)
+		if err == nil {
+			switch he := v.(type) {
+			case HTTPError:
+				err = he
+			case *HTTPError:
+				if he != nil {
+					err = he
+				}
+			}
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if c.ResponseWritten() {
+			return
+		}
+
+		switch r := v.(type) {
+		case nil:
+			c.NoContent()
+		case Result:
+			c.renderResult(r)
+		case *Result:
+			if r == nil {
+				c.NoContent()
+				return
+			}
+			c.renderResult(*r)
+		default:
+			c.JSON(http.StatusOK, v)
+		}
+	}
+}
+
+// renderResult writes r as JSON, or just its status without a body.
+func (c *Context) renderResult(r Result) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	switch {
+	case r.Status == http.StatusNoContent:
+		c.NoContent()
+	case r.Body == nil:
+		c.Response.WriteHeader(r.Status)
+	default:
+		c.JSON(r.Status, r.Body)
+	}
+}
@@ -0,0 +1,75 @@
+package onion
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandleErr ensures each return shape is rendered with the inferred status.
+func TestHandleErr(t *testing.T) {
+	app := New()
+	routes := map[string]func(c *Context) (interface{}, error){
+		"/value":      func(c *Context) (interface{}, error) { return map[string]int{"id": 1}, nil },
+		"/result":     func(c *Context) (interface{}, error) { return Created(map[string]int{"id": 2}), nil },
+		"/result-ptr": func(c *Context) (interface{}, error) { return &Result{Status: http.StatusAccepted}, nil },
+		"/nil":        func(c *Context) (interface{}, error) { return nil, nil },
+		"/http-error": func(c *Context) (interface{}, error) { return NewHTTPError(http.StatusConflict, "taken"), nil },
+		"/error":      func(c *Context) (interface{}, error) { return nil, NewHTTPError(http.StatusNotFound, "no book") },
+		"/bug":        func(c *Context) (interface{}, error) { return nil, errors.New("db down") },
+		"/written": func(c *Context) (interface{}, error) {
+			c.String(http.StatusTeapot, "tea")
+			return map[string]int{"id": 3}, nil
+		},
+	}
+	for path, fn := range routes {
+		app.GET(path, HandleErr(fn))
+	}
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/value", http.StatusOK, `{"id":1}`},
+		{"/result", http.StatusCreated, `{"id":2}`},
+		{"/result-ptr", http.StatusAccepted, ""},
+		{"/nil", http.StatusNoContent, ""},
+		{"/http-error", http.StatusConflict, `{"error":"taken"}`},
+		{"/error", http.StatusNotFound, `{"error":"no book"}`},
+		{"/bug", http.StatusInternalServerError, `{"error":"Internal Server Error"}`},
+		{"/written", http.StatusTeapot, "tea"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code || strings.TrimSpace(rec.Body.String()) != tt.body {
+			t.Errorf("%s: Expected %d '%s', got %d '%s'", tt.path, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestHandleErrAfterWrite ensures an error returned after a partial write is recorded, not lost.
+func TestHandleErrAfterWrite(t *testing.T) {
+	var errs []error
+	app := New()
+	app.Use(func(c *Context) {
+		c.Next()
+		errs = c.Errors()
+	})
+	app.GET("/export", HandleErr(func(c *Context) (interface{}, error) {
+		c.String(http.StatusOK, "id,title\n")
+		return nil, errors.New("db down")
+	}))
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/export", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "id,title\n" {
+		t.Errorf("Expected the partial body untouched, got %d '%s'", rec.Code, rec.Body.String())
+	}
+	if len(errs) != 1 || errs[0].Error() != "db down" {
+		t.Errorf("Expected the error to be recorded, got %v", errs)
+	}
+}
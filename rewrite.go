@@ -0,0 +1,68 @@
+package onion
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Pre-routing middleware (Pre, StripPrefix, RewritePath)
+// ----------------------------------------------------
+
+// Pre registers middleware that runs before the route is resolved, for
+// every request, matched or not. It may change c.Request (e.g. its path) to
+// affect routing, or answer and c.Abort() to stop there. Params are not
+// available yet. Middleware registered with Use runs later, once a route
+// matched.
+func (a *App) Pre(mw ...HandlerFunc) {
+	a.pre = append(a.pre, mw...)
+}
+
+// runPre runs the Pre chain and returns the request to route, or false if it aborted.
+func (a *App) runPre(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	c := a.newContext(w, r, nil)
+	c.handlers = a.pre
+	c.Next()
+	return c.Request, !c.IsAborted()
+}
+
+// StripPrefix removes prefix from the request path before routing, for apps
+// mounted under a path by a proxy: with StripPrefix("/api"), "/api/books"
+// is routed as "/books". Requests outside the prefix get the app's 404.
+// Register it with App.Pre.
+func StripPrefix(prefix string) HandlerFunc {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(c *Context) {
+		rest, ok := strings.CutPrefix(c.Request.URL.Path, prefix)
+		if !ok || (rest != "" && rest[0] != '/') {
+			c.app.notFound(c)
+			c.Abort()
+			return
+		}
+		if rest == "" {
+			rest = "/"
+		}
+		setRequestPath(c, rest)
+	}
+}
+
+// RewritePath replaces the request path with fn(path) before routing, e.g.
+// to map legacy URLs onto new routes. Register it with App.Pre.
+func RewritePath(fn func(path string) string) HandlerFunc {
+	return func(c *Context) {
+		setRequestPath(c, fn(c.Request.URL.Path))
+	}
+}
+
+// setRequestPath points c.Request at path, leaving the original request
+// (which outer net/http handlers may still hold) untouched.
+func setRequestPath(c *Context, path string) {
+	r := new(http.Request)
+	*r = *c.Request
+	r.URL = new(url.URL)
+	*r.URL = *c.Request.URL
+	r.URL.Path = path
+	r.URL.RawPath = ""
+	c.Request = r
+}
@@ -0,0 +1,58 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestStripPrefix ensures "/api/books" is routed to "/books" and other paths get 404.
+func TestStripPrefix(t *testing.T) {
+	app := New()
+	app.Pre(StripPrefix("/api/"))
+	app.handle("GET", "/books", func(c *Context) {
+		c.String(http.StatusOK, "books at "+c.Request.URL.Path)
+	})
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, "root")
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/books", http.StatusOK, "books at /books"},
+		{"/api", http.StatusOK, "root"},
+		{"/books", http.StatusNotFound, ""},
+		{"/apibooks", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected status code %d, got %d", tt.path, tt.code, rec.Code)
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s: expected body '%s', got '%s'", tt.path, tt.body, rec.Body.String())
+		}
+	}
+}
+
+// TestRewritePath ensures rewrites happen before routing.
+func TestRewritePath(t *testing.T) {
+	app := New()
+	app.Pre(RewritePath(func(path string) string {
+		return strings.Replace(path, "/v1/", "/v2/", 1)
+	}))
+	app.handle("GET", "/v2/books/:id", func(c *Context) {
+		c.String(http.StatusOK, "v2 book "+c.Param("id"))
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/books/7", nil))
+	if rec.Body.String() != "v2 book 7" {
+		t.Errorf("Expected the rewritten route, got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
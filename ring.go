@@ -0,0 +1,104 @@
+package onion
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------
+// Recent requests (RequestRing)
+// ----------------------------------------------------
+
+// RequestSummary describes a request recorded by RequestRing.
+type RequestSummary struct {
+	Time     time.Time     `json:"time"`
+	Method   string        `json:"method"`
+	Path     string        `json:"path"`
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"duration"` // in nanoseconds in JSON
+}
+
+// requestRing is a fixed-size circular buffer of request summaries.
+type requestRing struct {
+	mu      sync.Mutex
+	entries []RequestSummary
+	next    int
+	full    bool
+}
+
+func (r *requestRing) add(s RequestSummary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = s
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns the entries, oldest first.
+func (r *requestRing) snapshot() []RequestSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]RequestSummary(nil), r.entries[:r.next]...)
+	}
+	out := make([]RequestSummary, 0, len(r.entries))
+	out = append(out, r.entries[r.next:]...)
+	return append(out, r.entries[:r.next]...)
+}
+
+// RequestRing keeps the last size requests in memory, for a quick look at
+// live traffic in development without external tooling. Read them with
+// App.RecentRequests, or serve them with App.RecentRequestsHandler:
+//
+//	app.Use(onion.RequestRing(100))
+//	app.GET("/debug/requests", app.RecentRequestsHandler())
+//
+// Like Logger, register it early so the time spent in later middlewares is
+// counted.
+func RequestRing(size int) HandlerFunc {
+	if size <= 0 {
+		panic("onion: RequestRing size must be positive")
+	}
+	ring := &requestRing{entries: make([]RequestSummary, size)}
+	return func(c *Context) {
+		c.app.recent.Store(ring)
+		start := time.Now()
+		c.Next()
+		ring.add(RequestSummary{
+			Time:     start,
+			Method:   c.Request.Method,
+			Path:     c.Request.URL.Path,
+			Status:   c.writer.status,
+			Duration: time.Since(start),
+		})
+	}
+}
+
+// RecentRequests returns the requests recorded by RequestRing, oldest first.
+// It returns nil if RequestRing hasn't served a request yet.
+func (a *App) RecentRequests() []RequestSummary {
+	ring := a.recent.Load()
+	if ring == nil {
+		return nil
+	}
+	return ring.snapshot()
+}
+
+// RecentRequestsHandler serves RecentRequests as JSON, newest first. It
+// exposes request paths, so keep it off production or behind auth.
+func (a *App) RecentRequestsHandler() HandlerFunc {
+	return func(c *Context) {
+		recent := a.RecentRequests()
+		out := make([]RequestSummary, len(recent))
+		for i, s := range recent {
+			out[len(recent)-1-i] = s
+		}
+		c.NoCache()
+		c.JSON(http.StatusOK, out)
+	}
+}
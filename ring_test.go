@@ -0,0 +1,54 @@
+package onion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// TestRequestRing ensures the last N requests are kept, oldest first.
+func TestRequestRing(t *testing.T) {
+	app := New()
+	app.Use(RequestRing(3))
+	app.GET("/books/:id", func(c *Context) {
+		if c.Param("id") == "6" {
+			c.Error(NewHTTPError(http.StatusNotFound))
+			return
+		}
+		c.String(http.StatusOK, "book")
+	})
+	app.GET("/debug/requests", app.RecentRequestsHandler())
+
+	if recent := app.RecentRequests(); recent != nil {
+		t.Errorf("Expected no requests before any is served, got %v", recent)
+	}
+	for i := 1; i <= 6; i++ {
+		app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/books/"+strconv.Itoa(i), nil))
+	}
+
+	recent := app.RecentRequests()
+	want := []string{"GET /books/4 200", "GET /books/5 200", "GET /books/6 404"}
+	if len(recent) != len(want) {
+		t.Fatalf("Expected %d requests, got %d", len(want), len(recent))
+	}
+	for i, s := range recent {
+		if got := s.Method + " " + s.Path + " " + strconv.Itoa(s.Status); got != want[i] {
+			t.Errorf("Expected request %d to be '%s', got '%s'", i, want[i], got)
+		}
+		if s.Time.IsZero() || s.Duration <= 0 {
+			t.Errorf("Expected a time and duration, got %v", s)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/requests", nil))
+	var served []RequestSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatal(err)
+	}
+	if len(served) != 3 || served[0].Path != "/books/6" || served[2].Path != "/books/4" {
+		t.Errorf("Expected the last 3 requests newest first, got %v", served)
+	}
+}
@@ -0,0 +1,98 @@
+package onion
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Request scheme (http / https)
+// ----------------------------------------------------
+
+// TrustedProxies lists the proxies (IPs or CIDR ranges) whose
+// X-Forwarded-Proto and X-Forwarded-For headers are believed, for apps
+// behind a load balancer: they decide Context.Scheme and Context.ClientIP.
+// Requests from anywhere else are judged by their own connection. It panics
+// on a malformed entry.
+func (a *App) TrustedProxies(proxies ...string) {
+	a.trustedProxies = parsePrefixes(proxies)
+}
+
+// Scheme returns "https" for requests over TLS, or forwarded as such by a
+// trusted proxy (see App.TrustedProxies), and "http" otherwise.
+func (c *Context) Scheme() string {
+	return c.app.requestScheme(c.Request)
+}
+
+// requestScheme works out the scheme the client used.
+func (a *App) requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	if len(a.trustedProxies) > 0 {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" && a.fromTrustedProxy(r) {
+			// A chain of proxies may list several; the first is the client's
+			proto, _, _ = strings.Cut(proto, ",")
+			return strings.ToLower(strings.TrimSpace(proto))
+		}
+	}
+	return "http"
+}
+
+// fromTrustedProxy reports whether the request comes straight from a trusted proxy.
+func (a *App) fromTrustedProxy(r *http.Request) bool {
+	return a.trustedProxy(remoteIP(r))
+}
+
+// trustedProxy reports whether ip is one of the trusted proxies.
+func (a *App) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && containsAddr(a.trustedProxies, addr.Unmap())
+}
+
+// clientIP works out the client's IP, see Context.ClientIP.
+func (a *App) clientIP(r *http.Request) string {
+	ip := remoteIP(r)
+	if len(a.trustedProxies) == 0 || !a.trustedProxy(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		ip = hop
+		if !a.trustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// remoteIP is the IP of the connection, RemoteAddr without the port.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// WithScheme makes the route match only requests made over scheme ("http"
+// or "https", see Context.Scheme). Others fall through to the rest of the
+// route table, typically ending in a 404.
+func WithScheme(scheme string) RouteOption {
+	return func(r *Route) {
+		r.Scheme = scheme
+	}
+}
+
+// Scheme makes every route in the group match only requests made over
+// scheme, e.g. NewGroup("admin").Scheme("https") so admin routes are only
+// served over TLS. Set it before adding routes.
+func (rg *RouteGroup) Scheme(scheme string) *RouteGroup {
+	rg.scheme = scheme
+	return rg
+}
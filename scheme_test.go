@@ -0,0 +1,44 @@
+package onion
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGroupScheme ensures https-only routes match over TLS or a trusted proxy, and not over http.
+func TestGroupScheme(t *testing.T) {
+	app := New()
+	app.TrustedProxies("10.0.0.0/8")
+	app.UseRoutes(NewGroup("admin").Scheme("https").
+		GET("/stats", func(c *Context) { c.String(http.StatusOK, "stats over "+c.Scheme()) }).
+		Routes())
+
+	request := func(remote, proto string, secure bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/admin/stats", nil)
+		req.RemoteAddr = remote
+		if proto != "" {
+			req.Header.Set("X-Forwarded-Proto", proto)
+		}
+		if secure {
+			req.TLS = &tls.ConnectionState{}
+		}
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request("203.0.113.7:1", "", true); rec.Body.String() != "stats over https" {
+		t.Errorf("Expected a match over TLS, got %d '%s'", rec.Code, rec.Body.String())
+	}
+	if rec := request("10.1.1.1:1", "https", false); rec.Body.String() != "stats over https" {
+		t.Errorf("Expected a match behind a trusted proxy, got %d '%s'", rec.Code, rec.Body.String())
+	}
+	if rec := request("203.0.113.7:1", "", false); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code 404 over http, got %d", rec.Code)
+	}
+	if rec := request("203.0.113.7:1", "https", false); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected X-Forwarded-Proto from an untrusted client to be ignored, got %d", rec.Code)
+	}
+}
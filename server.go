@@ -0,0 +1,222 @@
+package onion
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/netutil"
+)
+
+// ----------------------------------------------------
+// Server lifecycle (Run, RunListener, Shutdown)
+// ----------------------------------------------------
+
+// SetKeepAlivesEnabled controls whether HTTP keep-alives are enabled.
+// By default keep-alives are always enabled. If the server is already
+// running the setting is applied to it immediately.
+func (a *App) SetKeepAlivesEnabled(v bool) {
+	a.serverMu.Lock()
+	defer a.serverMu.Unlock()
+
+	a.keepAlives = v
+	if a.server != nil {
+		a.server.SetKeepAlivesEnabled(v)
+	}
+}
+
+// MaxConnections limits the number of simultaneously accepted connections.
+// Connections beyond the limit are not rejected: they stay queued in the
+// kernel backlog until an active connection closes. 0 means unlimited.
+//
+// Note that with keep-alives enabled an idle client holds its slot until the
+// connection is closed, so you may want to combine a low limit with
+// SetKeepAlivesEnabled(false). On Shutdown the listener is closed first, so
+// queued connections are never accepted, while active ones are drained.
+func (a *App) MaxConnections(n int) {
+	a.serverMu.Lock()
+	defer a.serverMu.Unlock()
+
+	a.maxConns = n
+}
+
+// Run starts the server on the given address. It blocks until the server
+// stops: it returns nil after a Shutdown, and the error otherwise, e.g. when
+// addr can't be bound.
+func (a *App) Run(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return a.RunListener(ln)
+}
+
+// RunWithContext is Run until ctx is done, then shuts the server down
+// gracefully, e.g. with a context from signal.NotifyContext:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	if err := app.RunWithContext(ctx, ":3333"); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// It returns once in-flight requests have finished, with nil after a clean
+// shutdown. For a bounded wait, call Shutdown with a deadline yourself.
+func (a *App) RunWithContext(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv, ln := a.start(ln)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.serveUntilClosed(srv, ln)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	err = a.Shutdown(context.Background())
+	if serveErr := <-done; err == nil {
+		err = serveErr
+	}
+	return err
+}
+
+// RunOrRetry is Run over a list of addresses: it serves on the first one it
+// can bind, e.g. []string{":8080", ":8081", ":0"} for a dev server that
+// shouldn't fail on "address already in use". Like Run, it blocks until the
+// server stops, then returns the address it bound (with the actual port for
+// ":0") along with the serve error (nil after a Shutdown). The startup
+// banner shows that address too.
+// If none can be bound it returns "" and the bind errors, joined.
+func (a *App) RunOrRetry(addrs []string) (string, error) {
+	var errs []error
+	for _, addr := range addrs {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		return ln.Addr().String(), a.RunListener(ln)
+	}
+	if len(errs) == 0 {
+		return "", errors.New("onion: RunOrRetry needs at least one address")
+	}
+	return "", errors.Join(errs...)
+}
+
+// RunListener serves the app on an existing listener. This is handy for tests
+// (listen on ":0") or for socket activation. Like Run, it returns nil after a
+// Shutdown.
+func (a *App) RunListener(ln net.Listener) error {
+	srv, ln := a.start(ln)
+	return a.serveUntilClosed(srv, ln)
+}
+
+// start sets up the server for ln, so Shutdown can reach it right away.
+func (a *App) start(ln net.Listener) (*http.Server, net.Listener) {
+	a.serverMu.Lock()
+	if a.maxConns > 0 {
+		ln = netutil.LimitListener(ln, a.maxConns)
+	}
+	srv := a.newServer()
+	a.server = srv
+	a.startTasks()
+	a.serverMu.Unlock()
+
+	a.printBanner(ln.Addr().String())
+	return srv, ln
+}
+
+// serveUntilClosed serves ln until the server stops.
+func (a *App) serveUntilClosed(srv *http.Server, ln net.Listener) error {
+	err := srv.Serve(ln)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	// Shutdown stops the tasks otherwise
+	a.stopTasks(context.Background())
+	return err
+}
+
+// newServer builds the *http.Server for the app's settings.
+func (a *App) newServer() *http.Server {
+	srv := &http.Server{Handler: a.mux}
+	if a.maxHeaderBytes > 0 {
+		srv.MaxHeaderBytes = a.maxHeaderBytes
+	}
+	srv.SetKeepAlivesEnabled(a.keepAlives)
+	return srv
+}
+
+// Shutdown gracefully stops the server: the listener is closed right away and
+// active connections are given until ctx is done to finish. Background
+// tasks (see AddTask) are stopped too.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.serverMu.Lock()
+	srv := a.server
+	a.serverMu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+	err := srv.Shutdown(ctx)
+	if taskErr := a.stopTasks(ctx); err == nil {
+		err = taskErr
+	}
+	return err
+}
+
+// ----------------------------------------------------
+// Draining (zero-downtime deploys)
+// ----------------------------------------------------
+
+// Drain puts the app in drain mode before a Shutdown: Ready starts answering
+// 503 so load balancers stop sending new traffic, and keep-alives are turned
+// off so open connections close once their current request is answered.
+// Requests keep being served meanwhile. A zero-downtime deploy goes:
+//
+//	<-sigterm
+//	app.Drain()
+//	time.Sleep(15 * time.Second) // a few readiness probe periods
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	app.Shutdown(ctx) // in-flight requests finish
+//
+// Drain mode can't be left; it's meant for an app that's going away.
+func (a *App) Drain() {
+	a.draining.Store(true)
+
+	a.serverMu.Lock()
+	defer a.serverMu.Unlock()
+	if a.server != nil {
+		a.server.SetKeepAlivesEnabled(false)
+	}
+}
+
+// Draining reports whether Drain has been called.
+func (a *App) Draining() bool {
+	return a.draining.Load()
+}
+
+// Ready is a readiness probe handler: 200 {"status":"ready"}, or 503
+// {"status":"draining"} once Drain is called.
+//
+//	app.GET("/readyz", app.Ready())
+//
+// Liveness probes should use another route: a draining app is still alive.
+func (a *App) Ready() HandlerFunc {
+	return func(c *Context) {
+		c.NoCache()
+		if a.Draining() {
+			c.Response.Header().Set("Connection", "close")
+			c.JSON(http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+			return
+		}
+		c.JSON(http.StatusOK, map[string]string{"status": "ready"})
+	}
+}
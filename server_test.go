@@ -0,0 +1,341 @@
+package onion
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMaxConnections ensures connections beyond the limit are queued until a slot frees up.
+func TestMaxConnections(t *testing.T) {
+	app := New()
+	app.SetLogger(&captureLogger{})
+	app.MaxConnections(1)
+	app.SetKeepAlivesEnabled(false)
+
+	release := make(chan struct{})
+	app.handle("GET", "/slow", func(c *Context) {
+		<-release
+		c.String(http.StatusOK, "slow")
+	})
+	app.handle("GET", "/fast", func(c *Context) {
+		c.String(http.StatusOK, "fast")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.RunListener(ln)
+	defer app.Shutdown(context.Background())
+
+	base := "http://" + ln.Addr().String()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		resp, err := client.Get(base + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	// Give the slow request time to occupy the only slot
+	time.Sleep(100 * time.Millisecond)
+
+	fastDone := make(chan string)
+	go func() {
+		resp, err := client.Get(base + "/fast")
+		if err != nil {
+			fastDone <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		fastDone <- string(body)
+	}()
+
+	select {
+	case body := <-fastDone:
+		t.Fatalf("Expected second connection to be queued, got '%s'", body)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	close(release)
+	<-slowDone
+
+	select {
+	case body := <-fastDone:
+		if body != "fast" {
+			t.Errorf("Expected body 'fast', got '%s'", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected queued connection to be served after the slot freed up")
+	}
+}
+
+// TestSetKeepAlivesEnabled ensures the setting reaches the running server.
+func TestSetKeepAlivesEnabled(t *testing.T) {
+	app := New()
+	app.SetLogger(&captureLogger{})
+	app.SetKeepAlivesEnabled(false)
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.RunListener(ln)
+	defer app.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if !resp.Close {
+		t.Errorf("Expected server to close the connection with keep-alives disabled")
+	}
+}
+
+// TestServerMaxHeaderBytes ensures the header limit reaches the server, which rejects huge headers.
+func TestServerMaxHeaderBytes(t *testing.T) {
+	app := New()
+	if srv := app.newServer(); srv.MaxHeaderBytes != 0 {
+		t.Errorf("Expected the net/http default, got %d", srv.MaxHeaderBytes)
+	}
+
+	app.SetLogger(&captureLogger{})
+	app.MaxHeaderBytes(1024)
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	if srv := app.newServer(); srv.MaxHeaderBytes != 1024 {
+		t.Errorf("Expected MaxHeaderBytes 1024 on the server, got %d", srv.MaxHeaderBytes)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.RunListener(ln)
+	defer app.Shutdown(context.Background())
+
+	req, _ := http.NewRequest("GET", "http://"+ln.Addr().String()+"/", nil)
+	req.Header.Set("X-Padding", strings.Repeat("x", 16<<10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("Expected status code 431, got %d", resp.StatusCode)
+	}
+}
+
+// TestRunOrRetry ensures an occupied address is skipped and the next one is used.
+func TestRunOrRetry(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	type result struct {
+		addr string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		addr, err := app.RunOrRetry([]string{busy.Addr().String(), "127.0.0.1:0"})
+		done <- result{addr, err}
+	}()
+
+	for i := 0; ; i++ {
+		app.serverMu.Lock()
+		running := app.server != nil
+		app.serverMu.Unlock()
+		if running {
+			break
+		}
+		if i == 100 {
+			t.Fatal("Expected the server to start on the second address")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	app.Shutdown(context.Background())
+
+	res := <-done
+	host, port, _ := net.SplitHostPort(res.addr)
+	if host != "127.0.0.1" || port == "0" || res.addr == busy.Addr().String() || res.err != nil {
+		t.Errorf("Expected the second address and no error, got '%s' (%v)", res.addr, res.err)
+	}
+	if !strings.Contains(logger.String(), "running on "+res.addr) {
+		t.Errorf("Expected '%s' to be the bound address, got '%s'", res.addr, logger.String())
+	}
+
+	addr, err := New().RunOrRetry([]string{busy.Addr().String(), busy.Addr().String()})
+	if addr != "" || err == nil || !strings.Contains(err.Error(), "address already in use") {
+		t.Errorf("Expected the bind errors when every address is taken, got '%s' (%v)", addr, err)
+	}
+}
+
+// TestRunWithContext ensures a cancelled context shuts down cleanly with nil, unlike a bind failure.
+func TestRunWithContext(t *testing.T) {
+	app := New()
+	app.SetLogger(&captureLogger{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- app.RunWithContext(ctx, "127.0.0.1:0")
+	}()
+
+	for i := 0; ; i++ {
+		app.serverMu.Lock()
+		running := app.server != nil
+		app.serverMu.Unlock()
+		if running {
+			break
+		}
+		if i == 100 {
+			t.Fatal("Expected the server to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected nil after a clean shutdown, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected RunWithContext to return once the context is done")
+	}
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	if err := New().RunWithContext(context.Background(), busy.Addr().String()); err == nil {
+		t.Error("Expected an error for an address already in use")
+	}
+	if err := New().Run(busy.Addr().String()); err == nil {
+		t.Error("Expected Run to return the bind error")
+	}
+}
+
+// TestAddTask ensures tasks run while the server is up and stop on Shutdown.
+func TestAddTask(t *testing.T) {
+	app := New()
+	app.SetLogger(&captureLogger{})
+
+	var runs atomic.Int32
+	stopped := make(chan struct{})
+	var once sync.Once
+	app.AddTask(10*time.Millisecond, func(ctx context.Context) {
+		runs.Add(1)
+		context.AfterFunc(ctx, func() {
+			once.Do(func() { close(stopped) })
+		})
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.RunListener(ln)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runs.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if runs.Load() == 0 {
+		t.Fatal("Expected the task to run at least once")
+	}
+
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the task context to be cancelled on Shutdown")
+	}
+
+	after := runs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != after {
+		t.Errorf("Expected no runs after Shutdown, got %d more", runs.Load()-after)
+	}
+}
+
+// TestDrain ensures Ready flips to 503 on Drain while in-flight requests complete.
+func TestDrain(t *testing.T) {
+	app := New()
+	app.SetLogger(&captureLogger{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	app.GET("/readyz", app.Ready())
+	app.GET("/slow", func(c *Context) {
+		close(started)
+		<-release
+		c.String(http.StatusOK, "slow")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.RunListener(ln)
+	defer app.Shutdown(context.Background())
+	base := "http://" + ln.Addr().String()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	if code, body := get("/readyz"); code != http.StatusOK || body != `{"status":"ready"}` {
+		t.Errorf("Expected 200 ready before draining, got %d '%s'", code, body)
+	}
+
+	slow := make(chan string)
+	go func() {
+		code, body := get("/slow")
+		slow <- strconv.Itoa(code) + " " + body
+	}()
+	<-started
+
+	app.Drain()
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || body != `{"status":"draining"}` {
+		t.Errorf("Expected 503 draining, got %d '%s'", code, body)
+	}
+	if !app.Draining() {
+		t.Error("Expected Draining to report true")
+	}
+
+	close(release)
+	if got := <-slow; got != "200 slow" {
+		t.Errorf("Expected the in-flight request to complete, got '%s'", got)
+	}
+}
@@ -0,0 +1,69 @@
+package onion
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// TestVerifySignature ensures a valid signature passes and the handler can still read the body.
+func TestVerifySignature(t *testing.T) {
+	app := New()
+	app.Use(VerifySignature(SignatureConfig{Secret: []byte("s3cret")}))
+	app.handle("POST", "/hook", func(c *Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(data))
+	})
+
+	body := `{"action":"opened"}`
+	req := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("s3cret", body))
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", rec.Code)
+	}
+	if rec.Body.String() != body {
+		t.Errorf("Expected body '%s', got '%s'", body, rec.Body.String())
+	}
+}
+
+// TestVerifySignatureForged ensures forged or missing signatures are rejected.
+func TestVerifySignatureForged(t *testing.T) {
+	app := New()
+	app.Use(VerifySignature(SignatureConfig{
+		Secret: []byte("s3cret"),
+		Header: "X-Signature",
+	}))
+	called := false
+	app.handle("POST", "/hook", func(c *Context) {
+		called = true
+	})
+
+	body := `{"action":"opened"}`
+	for _, sig := range []string{sign("wrong", body), "", "sha256=zz"} {
+		req := httptest.NewRequest("POST", "/hook", strings.NewReader(body))
+		req.Header.Set("X-Signature", sig)
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status code 401 for signature '%s', got %d", sig, rec.Code)
+		}
+	}
+	if called {
+		t.Errorf("Expected handler not to run for forged signatures")
+	}
+}
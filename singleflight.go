@@ -0,0 +1,108 @@
+package onion
+
+import (
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// ----------------------------------------------------
+// Coalescing duplicate requests (singleflight)
+// ----------------------------------------------------
+
+// flight is one running handler that identical requests wait on.
+type flight struct {
+	done chan struct{}
+	resp *IdempotentResponse // nil if the handler failed
+}
+
+// SingleFlight makes concurrent identical GET requests share one handler
+// run: the first request runs the chain, and the ones arriving while it's
+// still running wait for it and get a copy of its status, headers and body.
+// This keeps a cache miss on an expensive endpoint from turning into a
+// thundering herd. Headers set before SingleFlight runs, like the request
+// ID, stay each request's own.
+//
+// Requests are identical when they have the same host and URI (path and
+// query); other request headers are ignored, so only use it on routes whose
+// response doesn't depend on cookies or credentials. Nothing is kept once
+// the first request finishes. If it fails (a 5xx or a panic), the waiting
+// requests run the handler themselves instead of sharing the failure.
+func SingleFlight() HandlerFunc {
+	var (
+		mu      sync.Mutex
+		flights = make(map[string]*flight)
+	)
+	return func(c *Context) {
+		if c.Request.Method != http.MethodGet {
+			return
+		}
+		key := c.Request.Host + " " + c.Request.URL.RequestURI()
+
+		mu.Lock()
+		f, waiting := flights[key]
+		if !waiting {
+			f = &flight{done: make(chan struct{})}
+			flights[key] = f
+		}
+		mu.Unlock()
+
+		if waiting {
+			select {
+			case <-f.done:
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+			if f.resp != nil {
+				h := c.Response.Header()
+				for k, v := range f.resp.Header {
+					h[k] = append([]string(nil), v...)
+				}
+				c.Response.WriteHeader(f.resp.Status)
+				c.Response.Write(f.resp.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		orig := c.Response
+		before := orig.Header().Clone()
+		rec := &recordingWriter{ResponseWriter: orig}
+		c.Response = rec
+		defer func() {
+			c.Response = orig
+			mu.Lock()
+			delete(flights, key)
+			mu.Unlock()
+			close(f.done)
+		}()
+
+		c.Next()
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status < 500 {
+			f.resp = &IdempotentResponse{
+				Status: status,
+				Header: headersSetSince(before, orig.Header()),
+				Body:   rec.body.Bytes(),
+			}
+		}
+	}
+}
+
+// headersSetSince returns copies of the headers in after that are new or
+// changed since before: the ones set by the rest of the chain, as opposed to
+// per-request headers set earlier (X-Request-ID, rate limits, cookies).
+func headersSetSince(before, after http.Header) http.Header {
+	set := make(http.Header)
+	for k, v := range after {
+		if !slices.Equal(before[k], v) {
+			set[k] = append([]string(nil), v...)
+		}
+	}
+	return set
+}
@@ -0,0 +1,106 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSingleFlight ensures concurrent identical requests share one handler run.
+func TestSingleFlight(t *testing.T) {
+	var runs atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+
+	var ids atomic.Int32
+	app := New()
+	app.Use(func(c *Context) {
+		c.Response.Header().Set("X-Request-ID", strconv.Itoa(int(ids.Add(1))))
+	})
+	app.Use(SingleFlight())
+	app.handle("GET", "/report", func(c *Context) {
+		if runs.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		c.Response.Header().Set("X-Report", "weekly")
+		c.String(http.StatusOK, "expensive")
+	})
+
+	const n = 20
+	recs := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	serve := func(i int) {
+		defer wg.Done()
+		recs[i] = httptest.NewRecorder()
+		app.mux.ServeHTTP(recs[i], httptest.NewRequest("GET", "/report?week=12", nil))
+	}
+
+	wg.Add(n)
+	go serve(0)
+	<-started
+	for i := 1; i < n; i++ {
+		go serve(i)
+	}
+	time.Sleep(50 * time.Millisecond) // let the others queue up behind the first
+	close(release)
+	wg.Wait()
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected the handler to run once, ran %d times", got)
+	}
+	seen := make(map[string]bool)
+	for i, rec := range recs {
+		if rec.Code != http.StatusOK || rec.Body.String() != "expensive" || rec.Header().Get("X-Report") != "weekly" {
+			t.Errorf("Request %d: expected the shared response, got %d '%s'", i, rec.Code, rec.Body.String())
+		}
+		if id := rec.Header().Get("X-Request-ID"); seen[id] {
+			t.Errorf("Request %d: expected its own request ID, got the shared '%s'", i, id)
+		}
+		seen[rec.Header().Get("X-Request-ID")] = true
+	}
+}
+
+// TestSingleFlightError ensures a failed run isn't shared with the waiting requests.
+func TestSingleFlightError(t *testing.T) {
+	var runs atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+
+	app := New()
+	app.Use(SingleFlight())
+	app.handle("GET", "/report", func(c *Context) {
+		if runs.Add(1) == 1 {
+			close(started)
+			<-release
+			c.Error(NewHTTPError(http.StatusServiceUnavailable))
+			return
+		}
+		c.String(http.StatusOK, "expensive")
+	})
+
+	first, second := httptest.NewRecorder(), httptest.NewRecorder()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		app.mux.ServeHTTP(first, httptest.NewRequest("GET", "/report", nil))
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		app.mux.ServeHTTP(second, httptest.NewRequest("GET", "/report", nil))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if first.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code 503 for the first request, got %d", first.Code)
+	}
+	if second.Code != http.StatusOK || second.Body.String() != "expensive" {
+		t.Errorf("Expected the second request to run the handler itself, got %d '%s'", second.Code, second.Body.String())
+	}
+}
@@ -0,0 +1,250 @@
+package onion
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------
+// Static files
+// ----------------------------------------------------
+
+// StaticOption customizes a Static or StaticFile mount.
+type StaticOption func(*staticConfig)
+
+type staticConfig struct {
+	notFound  HandlerFunc
+	apiPrefix string        // see SPAAPIPrefix
+	maxAge    time.Duration // see StaticMaxAge
+	noCache   bool          // the SPA index
+}
+
+// StaticNotFound sets the handler used when a file is missing from this
+// mount, so asset 404s can differ from API 404s. Without it the app's
+// NotFoundHandler is used.
+func StaticNotFound(fn HandlerFunc) StaticOption {
+	return func(sc *staticConfig) {
+		sc.notFound = fn
+	}
+}
+
+// StaticMaxAge lets browsers and CDNs cache the files for maxAge (see
+// Context.CachePublic), typically long for fingerprinted assets like
+// app.3f9a1c.js. index.html files get NoCache instead, since they point at
+// the current assets. Without it no Cache-Control is sent and browsers
+// revalidate with Last-Modified.
+func StaticMaxAge(maxAge time.Duration) StaticOption {
+	return func(sc *staticConfig) {
+		sc.maxAge = maxAge
+	}
+}
+
+// SPAAPIPrefix sets the path prefix SPA treats as API, "/api" by default.
+func SPAAPIPrefix(prefix string) StaticOption {
+	return func(sc *staticConfig) {
+		sc.apiPrefix = "/" + strings.Trim(prefix, "/")
+	}
+}
+
+// SPA serves a single-page app from staticDir alongside the API. Requests
+// that no route matches are answered from staticDir: a GET or HEAD for an
+// existing file gets the file, and any other GET or HEAD gets indexFile, so
+// client-side routes like /books/42 load the app. The index is sent with
+// NoCache, so a deploy is picked up on the next load, while StaticMaxAge
+// applies to the other files.
+//
+// API paths ("/api" and below, see SPAAPIPrefix) are never answered with
+// the app: unmatched ones get the usual 404, and registered routes always
+// win over the SPA. Other methods get the usual 404 or 405 too.
+func (a *App) SPA(staticDir, indexFile string, opts ...StaticOption) {
+	sc := a.staticConfig(opts)
+	if sc.apiPrefix == "" {
+		sc.apiPrefix = "/api"
+	}
+	fsys := os.DirFS(staticDir)
+	index := &staticConfig{notFound: sc.notFound, noCache: true}
+
+	a.spa = func(c *Context) {
+		p := c.Request.URL.Path
+		api := p == sc.apiPrefix || strings.HasPrefix(p, sc.apiPrefix+"/")
+		if api || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			a.notFound(c)
+			return
+		}
+		a.serveStatic(c, fsys, p, &staticConfig{maxAge: sc.maxAge, notFound: func(c *Context) {
+			a.serveStatic(c, fsys, indexFile, index)
+		}})
+	}
+}
+
+// Static serves the files under root at urlPrefix, e.g.
+// app.Static("/assets", "./public") serves ./public/css/app.css at
+// /assets/css/app.css. Directories serve their index.html, if any.
+func (a *App) Static(urlPrefix, root string, opts ...StaticOption) {
+	a.StaticFS(urlPrefix, os.DirFS(root), opts...)
+}
+
+// StaticFS is Static for any fs.FS, typically an embed.FS:
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	sub, _ := fs.Sub(public, "public")
+//	app.StaticFS("/assets", sub)
+func (a *App) StaticFS(urlPrefix string, fsys fs.FS, opts ...StaticOption) {
+	sc := a.staticConfig(opts)
+
+	a.handle(http.MethodGet, strings.TrimRight(urlPrefix, "/")+"/*filepath", func(c *Context) {
+		a.serveStatic(c, fsys, c.Param("filepath"), sc)
+	})
+}
+
+// StaticFile serves a single file at urlPath, e.g. app.StaticFile("/favicon.ico", "./public/favicon.ico").
+func (a *App) StaticFile(urlPath, file string, opts ...StaticOption) {
+	sc := a.staticConfig(opts)
+	fsys := os.DirFS(path.Dir(file))
+	name := path.Base(file)
+
+	a.handle(http.MethodGet, urlPath, func(c *Context) {
+		a.serveStatic(c, fsys, name, sc)
+	})
+}
+
+// FileFS serves the named file from fsys, with the same content types,
+// conditional requests and 404s as Static.
+func (c *Context) FileFS(fsys fs.FS, name string) {
+	c.app.serveStatic(c, fsys, name, &staticConfig{})
+}
+
+// Download streams content from reader to the client as an attachment
+// named filename, for generated reports and exports that aren't files on
+// disk. size sets Content-Length; pass -1 if it isn't known. The content
+// type is the one already set on the response, else guessed from the file
+// extension, else sniffed from the first bytes. The response is flushed as
+// it's copied, so large payloads start arriving right away.
+func (c *Context) Download(reader io.Reader, filename string, size int64) error {
+	h := c.Response.Header()
+	if h.Get("Content-Type") == "" {
+		ctype := mime.TypeByExtension(path.Ext(filename))
+		if ctype == "" {
+			br := bufio.NewReader(reader)
+			head, _ := br.Peek(512)
+			ctype = http.DetectContentType(head)
+			reader = br
+		}
+		h.Set("Content-Type", ctype)
+	}
+	h.Set("Content-Disposition", attachmentDisposition(filename))
+	if size >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	c.Response.WriteHeader(http.StatusOK)
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			if _, werr := c.Response.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			c.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// attachmentDisposition builds the Content-Disposition for filename.
+// Control characters are replaced with "_", and if there's no name or it
+// still can't be formatted, the header is a plain "attachment".
+func attachmentDisposition(filename string) string {
+	filename = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, filename)
+	if filename != "" {
+		if v := mime.FormatMediaType("attachment", map[string]string{"filename": filename}); v != "" {
+			return v
+		}
+	}
+	return "attachment"
+}
+
+func (a *App) staticConfig(opts []StaticOption) *staticConfig {
+	sc := &staticConfig{}
+	for _, opt := range opts {
+		opt(sc)
+	}
+	return sc
+}
+
+// serveStatic writes the named file from fsys, or falls back to the 404 handler.
+func (a *App) serveStatic(c *Context, fsys fs.FS, name string, sc *staticConfig) {
+	notFound := sc.notFound
+	if notFound == nil {
+		notFound = a.notFound
+	}
+
+	// Clean the path so "../" can't escape the root
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	f, info, err := openStatic(fsys, name)
+	if err != nil {
+		notFound(c)
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			notFound(c)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	switch {
+	case sc.noCache || (info.Name() == "index.html" && sc.maxAge > 0):
+		c.NoCache()
+	case sc.maxAge > 0:
+		c.CachePublic(sc.maxAge)
+	}
+	http.ServeContent(c.Response, c.Request, info.Name(), info.ModTime(), content)
+}
+
+// openStatic opens a regular file, or a directory's index.html.
+func openStatic(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return openStatic(fsys, path.Join(name, "index.html"))
+	}
+	return f, info, nil
+}
@@ -0,0 +1,220 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func writeStaticFiles(t *testing.T) string {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "css"), 0o755)
+	os.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body{}"), 0o644)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>home</h1>"), 0o644)
+	return dir
+}
+
+// TestStatic ensures files are served with a content type and directories serve index.html.
+func TestStatic(t *testing.T) {
+	app := New()
+	app.Static("/assets", writeStaticFiles(t))
+
+	req := httptest.NewRequest("GET", "/assets/css/app.css", nil)
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "body{}" {
+		t.Errorf("Expected 200 'body{}', got %d '%s'", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("Expected a text/css content type, got '%s'", ct)
+	}
+
+	req = httptest.NewRequest("GET", "/assets/", nil)
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "<h1>home</h1>" {
+		t.Errorf("Expected index.html, got '%s'", rec.Body.String())
+	}
+}
+
+// TestStaticNotFound ensures a missing asset uses the mount's 404 while API 404s stay untouched.
+func TestStaticNotFound(t *testing.T) {
+	app := New()
+	app.NotFoundHandler(func(c *Context) {
+		c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	})
+	app.Static("/assets", writeStaticFiles(t), StaticNotFound(func(c *Context) {
+		c.String(http.StatusNotFound, "<h1>asset missing</h1>")
+	}))
+
+	for _, path := range []string{"/assets/missing.png", "/assets/../../etc/passwd"} {
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		// Skip the mux, which would clean the path and redirect
+		app.dispatch(rec, req)
+
+		if rec.Code != http.StatusNotFound || rec.Body.String() != "<h1>asset missing</h1>" {
+			t.Errorf("Expected the static 404 for '%s', got %d '%s'", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/books", nil)
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if !strings.Contains(rec.Body.String(), `"error"`) {
+		t.Errorf("Expected the API 404, got '%s'", rec.Body.String())
+	}
+}
+
+// TestStaticFile ensures a single file can be mounted, with its own 404.
+func TestStaticFile(t *testing.T) {
+	dir := writeStaticFiles(t)
+	app := New()
+	app.StaticFile("/home", filepath.Join(dir, "index.html"))
+	app.StaticFile("/gone", filepath.Join(dir, "gone.html"), StaticNotFound(func(c *Context) {
+		c.String(http.StatusNotFound, "gone")
+	}))
+
+	req := httptest.NewRequest("GET", "/home", nil)
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "<h1>home</h1>" {
+		t.Errorf("Expected index.html, got '%s'", rec.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/gone", nil)
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "gone" {
+		t.Errorf("Expected the custom 404, got '%s'", rec.Body.String())
+	}
+}
+
+// TestStaticFS ensures files are served from an in-memory filesystem, with 404s for missing ones.
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"js/app.js":  {Data: []byte("console.log(1)")},
+		"index.html": {Data: []byte("<h1>embedded</h1>")},
+	}
+
+	app := New()
+	app.StaticFS("/assets", fsys)
+	app.handle("GET", "/download", func(c *Context) {
+		c.FileFS(fsys, "js/app.js")
+	})
+
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+	}{
+		{"/assets/js/app.js", http.StatusOK, "text/javascript"},
+		{"/assets/", http.StatusOK, "text/html"},
+		{"/assets/missing.js", http.StatusNotFound, ""},
+		{"/download", http.StatusOK, "text/javascript"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s: expected status code %d, got %d", tt.path, tt.code, rec.Code)
+		}
+		if !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.contentType) {
+			t.Errorf("%s: expected content type '%s', got '%s'", tt.path, tt.contentType, rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+// TestSPA ensures assets are served, client routes get the index and API paths keep their 404.
+func TestSPA(t *testing.T) {
+	app := New()
+	app.GET("/api/books", func(c *Context) { c.String(http.StatusOK, "books") })
+	app.SPA(writeStaticFiles(t), "index.html")
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/css/app.css", http.StatusOK, "body{}"},
+		{"GET", "/books/42", http.StatusOK, "<h1>home</h1>"},
+		{"GET", "/", http.StatusOK, "<h1>home</h1>"},
+		{"GET", "/api/books", http.StatusOK, "books"},
+		{"GET", "/api/missing", http.StatusNotFound, ""},
+		{"POST", "/books/42", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s %s: expected %d '%s', got %d '%s'", tt.method, tt.path, tt.code, tt.body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestStaticMaxAge ensures assets are cacheable while index.html is not.
+func TestStaticMaxAge(t *testing.T) {
+	app := New()
+	app.Static("/assets", writeStaticFiles(t), StaticMaxAge(24*time.Hour))
+
+	for path, want := range map[string]string{
+		"/assets/css/app.css": "public, max-age=86400",
+		"/assets/":            "no-store, no-cache, must-revalidate",
+	} {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if got := rec.Header().Get("Cache-Control"); got != want {
+			t.Errorf("%s: expected '%s', got '%s'", path, want, got)
+		}
+	}
+}
+
+// TestDownload ensures generated content is sent as an attachment with its type and length.
+func TestDownload(t *testing.T) {
+	app := New()
+	app.GET("/report.csv", func(c *Context) {
+		c.Download(strings.NewReader("id,title\n1,dune\n"), "report 2024.csv", 17)
+	})
+	app.GET("/export", func(c *Context) {
+		c.Download(strings.NewReader("%PDF-1.7 ..."), "export", -1)
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/report.csv", nil))
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="report 2024.csv"` {
+		t.Errorf("Expected an attachment disposition, got '%s'", got)
+	}
+	if rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" || rec.Header().Get("Content-Length") != "17" {
+		t.Errorf("Expected text/csv with a length of 17, got %v", rec.Header())
+	}
+	if rec.Body.String() != "id,title\n1,dune\n" || !rec.Flushed {
+		t.Errorf("Expected the streamed body, got '%s'", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/export", nil))
+	if rec.Header().Get("Content-Type") != "application/pdf" || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Expected a sniffed type without a length, got %v", rec.Header())
+	}
+
+	for name, want := range map[string]string{
+		"bad\nname.csv": `attachment; filename=bad_name.csv`,
+		"\x00":          `attachment; filename=_`,
+		"":              "attachment",
+	} {
+		if got := attachmentDisposition(name); got != want {
+			t.Errorf("%q: Expected '%s', got '%s'", name, want, got)
+		}
+	}
+}
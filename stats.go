@@ -0,0 +1,78 @@
+package onion
+
+import (
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------
+// Per-route statistics
+// ----------------------------------------------------
+
+// RouteStats is a summary of the calls made to a single route.
+type RouteStats struct {
+	Count  int64         // number of requests handled
+	Errors int64         // number of responses with a 5xx status
+	Total  time.Duration // total time spent in middleware + handler
+	Max    time.Duration // slowest request seen so far
+}
+
+// Average returns the mean latency, or 0 if the route was never called.
+func (s RouteStats) Average() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+type statsTable struct {
+	mu     sync.Mutex
+	routes map[string]*RouteStats
+}
+
+func (t *statsTable) record(key string, status int, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.routes[key]
+	if !ok {
+		s = &RouteStats{}
+		t.routes[key] = s
+	}
+	s.Count++
+	if status >= 500 {
+		s.Errors++
+	}
+	s.Total += elapsed
+	if elapsed > s.Max {
+		s.Max = elapsed
+	}
+}
+
+// EnableStats turns per-route statistics on or off. It is off by default,
+// since it adds a lock and a clock read to every request.
+func (a *App) EnableStats(on bool) {
+	if !on {
+		a.stats.Store(nil)
+		return
+	}
+	a.stats.CompareAndSwap(nil, &statsTable{routes: make(map[string]*RouteStats)})
+}
+
+// Stats returns a snapshot of the collected statistics, keyed by
+// "METHOD pattern" (e.g. "GET /books/:bookId"). Unmatched requests are not counted.
+func (a *App) Stats() map[string]RouteStats {
+	t := a.stats.Load()
+	if t == nil {
+		return map[string]RouteStats{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[string]RouteStats, len(t.routes))
+	for k, s := range t.routes {
+		out[k] = *s
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestStats ensures counts, errors and latencies are recorded per route.
+func TestStats(t *testing.T) {
+	app := New()
+	app.EnableStats(true)
+
+	app.handle("GET", "/books/:bookId", func(c *Context) {
+		time.Sleep(5 * time.Millisecond)
+		c.String(http.StatusOK, "book")
+	})
+	app.handle("POST", "/books", func(c *Context) {
+		c.String(http.StatusInternalServerError, "boom")
+	})
+
+	for _, path := range []string{"/books/1", "/books/2", "/books/3"} {
+		app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/books", nil))
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nope", nil))
+
+	stats := app.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected stats for 2 routes, got %d", len(stats))
+	}
+
+	get := stats["GET /books/:bookId"]
+	if get.Count != 3 || get.Errors != 0 {
+		t.Errorf("Expected 3 calls and 0 errors, got %d and %d", get.Count, get.Errors)
+	}
+	if get.Max < 5*time.Millisecond || get.Total < 15*time.Millisecond {
+		t.Errorf("Expected latencies to be recorded, got max %v total %v", get.Max, get.Total)
+	}
+	if get.Average() < 5*time.Millisecond {
+		t.Errorf("Expected average of at least 5ms, got %v", get.Average())
+	}
+
+	post := stats["POST /books"]
+	if post.Count != 1 || post.Errors != 1 {
+		t.Errorf("Expected 1 call and 1 error, got %d and %d", post.Count, post.Errors)
+	}
+}
+
+// TestStatsDisabled ensures nothing is recorded unless stats are enabled.
+func TestStatsDisabled(t *testing.T) {
+	app := New()
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if len(app.Stats()) != 0 {
+		t.Errorf("Expected no stats when disabled, got %v", app.Stats())
+	}
+}
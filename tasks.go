@@ -0,0 +1,98 @@
+package onion
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------
+// Background tasks
+// ----------------------------------------------------
+
+type task struct {
+	interval time.Duration
+	fn       func(ctx context.Context)
+}
+
+// taskRunner runs the app's tasks while the server is up.
+type taskRunner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// AddTask runs fn every interval while the server is running, e.g. to
+// refresh a cache or purge expired sessions. Tasks start with Run (or
+// RunListener), the first run one interval later, and stop on Shutdown:
+// ctx is cancelled then, and Shutdown waits for running calls to return.
+// A task added while the server runs starts right away.
+//
+// Runs of one task never overlap: a run that takes longer than interval
+// delays the next one.
+func (a *App) AddTask(interval time.Duration, fn func(ctx context.Context)) {
+	if interval <= 0 {
+		panic("onion: AddTask needs a positive interval")
+	}
+	t := task{interval: interval, fn: fn}
+
+	a.serverMu.Lock()
+	defer a.serverMu.Unlock()
+
+	a.tasks = append(a.tasks, t)
+	if a.taskRunner != nil {
+		a.taskRunner.start(t)
+	}
+}
+
+// startTasks starts every task. The caller holds serverMu.
+func (a *App) startTasks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := &taskRunner{ctx: ctx, cancel: cancel}
+	for _, t := range a.tasks {
+		tr.start(t)
+	}
+	a.taskRunner = tr
+}
+
+// stopTasks cancels the tasks and waits for them until ctx is done.
+func (a *App) stopTasks(ctx context.Context) error {
+	a.serverMu.Lock()
+	tr := a.taskRunner
+	a.taskRunner = nil
+	a.serverMu.Unlock()
+
+	if tr == nil {
+		return nil
+	}
+	tr.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		tr.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (tr *taskRunner) start(t task) {
+	tr.wg.Add(1)
+	go func() {
+		defer tr.wg.Done()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-tr.ctx.Done():
+				return
+			case <-ticker.C:
+				t.fn(tr.ctx)
+			}
+		}
+	}()
+}
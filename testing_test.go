@@ -0,0 +1,48 @@
+package onion
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func getBookHandler(c *Context) {
+	if c.Request.Header.Get("X-Auth") == "" {
+		c.String(http.StatusUnauthorized, "Unauthorized!")
+		return
+	}
+	c.String(http.StatusOK, "Book ID: "+c.Param("bookId"))
+}
+
+func ExampleNewTestContext() {
+	c, rec := NewTestContext("GET", "/books/42", nil,
+		WithTestParam("bookId", "42"), WithTestHeader("X-Auth", "token"))
+
+	getBookHandler(c)
+
+	fmt.Println(rec.Code, rec.Body.String())
+	// Output: 200 Book ID: 42
+}
+
+// TestNewTestContext ensures the test context is fully usable by handlers.
+func TestNewTestContext(t *testing.T) {
+	c, rec := NewTestContext("POST", "/books", strings.NewReader(`{"id":7}`))
+
+	var book struct {
+		ID int `json:"id"`
+	}
+	if err := c.BindJSON(&book); err != nil || book.ID != 7 {
+		t.Errorf("Expected to bind the body, got %+v (%v)", book, err)
+	}
+
+	c.Set("user", "alice")
+	if v, _ := c.Get("user"); v != "alice" {
+		t.Errorf("Expected the store to work, got %v", v)
+	}
+
+	c.Error(NewHTTPError(http.StatusConflict, "exists"))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status code 409, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package onion
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------
+// Request timeouts
+// ----------------------------------------------------
+
+// HeaderTimeout lets clients pick their own deadline with a header like
+// "X-Request-Timeout: 5s" (any time.ParseDuration value). The value is capped
+// at max; a missing, malformed or non-positive value falls back to max. With
+// max <= 0 there is no cap, and no timeout unless the client sends one.
+//
+// The deadline is applied to c.Request.Context(), so handlers (and the DB
+// or HTTP calls they make with that context) need to honor it. Once the
+// rest of the chain returns, the original request is put back.
+func HeaderTimeout(header string, max time.Duration) HandlerFunc {
+	return func(c *Context) {
+		timeout := max
+		if d, err := time.ParseDuration(c.Request.Header.Get(header)); err == nil && d > 0 {
+			if max <= 0 || d < max {
+				timeout = d
+			}
+		}
+		if timeout <= 0 {
+			return
+		}
+
+		orig := c.Request
+		ctx, cancel := context.WithTimeout(orig.Context(), timeout)
+		defer cancel()
+		c.Request = orig.WithContext(ctx)
+		c.Next()
+		c.Request = orig
+	}
+}
+
+// DeadlineHeader carries a request's deadline between services, as a Unix
+// timestamp in seconds, possibly fractional ("1715000000.25"). See Budget.
+const DeadlineHeader = "X-Request-Deadline"
+
+// Budget propagates deadlines across services. It reads DeadlineHeader and
+// applies that deadline to c.Request.Context(), but never further out than
+// max from now (with max <= 0, no cap). A missing or malformed header leaves
+// the context as it is; a deadline already past cancels it right away. Like
+// HeaderTimeout, it puts the original request back once the chain returns.
+//
+// Upstream calls made with c.HTTPClient() then send DeadlineHeader with
+// the request's effective deadline (including Timeout), so the next service
+// works within what's left of the budget instead of starting afresh.
+func Budget(max time.Duration) HandlerFunc {
+	return func(c *Context) {
+		c.budget = true
+		deadline, ok := parseDeadline(c.Request.Header.Get(DeadlineHeader))
+		if limit := time.Now().Add(max); max > 0 && (!ok || deadline.After(limit)) {
+			deadline, ok = limit, true
+		}
+		if !ok {
+			return
+		}
+
+		orig := c.Request
+		ctx, cancel := context.WithDeadline(orig.Context(), deadline)
+		defer cancel()
+		c.Request = orig.WithContext(ctx)
+		c.Next()
+		c.Request = orig
+	}
+}
+
+// parseDeadline parses a DeadlineHeader value.
+func parseDeadline(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil || secs <= 0 || math.IsInf(secs, 0) {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(secs*float64(time.Second))), true
+}
+
+// formatDeadline renders t as a DeadlineHeader value, with millisecond precision.
+func formatDeadline(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+}
+
+// Timeout sets a deadline on every request's context. 0 (the default) means none.
+func (a *App) Timeout(d time.Duration) {
+	a.timeout = d
+}
+
+// MethodTimeouts overrides Timeout per HTTP method, e.g. a short deadline for
+// GET and a long one for POST uploads. Methods without an entry use Timeout.
+// Like Timeout, the deadline lives on c.Request.Context() and handlers need
+// to honor it.
+func (a *App) MethodTimeouts(timeouts map[string]time.Duration) {
+	m := make(map[string]time.Duration, len(timeouts))
+	for method, d := range timeouts {
+		m[strings.ToUpper(method)] = d
+	}
+	a.methodTimeouts = m
+}
+
+// requestTimeout returns the deadline for a request method, 0 for none.
+func (a *App) requestTimeout(method string) time.Duration {
+	if d, ok := a.methodTimeouts[method]; ok {
+		return d
+	}
+	return a.timeout
+}
@@ -0,0 +1,192 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestHeaderTimeout ensures the header value is parsed, capped, and falls back to max.
+func TestHeaderTimeout(t *testing.T) {
+	app := New()
+	app.Use(HeaderTimeout("X-Request-Timeout", 10*time.Second))
+
+	var remaining time.Duration
+	app.handle("GET", "/", func(c *Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			t.Fatalf("Expected a deadline on the request context")
+		}
+		remaining = time.Until(deadline)
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"2s", 1 * time.Second, 2 * time.Second},    // valid
+		{"1h", 9 * time.Second, 10 * time.Second},   // over the cap
+		{"soon", 9 * time.Second, 10 * time.Second}, // malformed
+		{"", 9 * time.Second, 10 * time.Second},     // missing
+		{"-5s", 9 * time.Second, 10 * time.Second},  // not positive
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			req.Header.Set("X-Request-Timeout", tt.header)
+		}
+		app.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+		if remaining < tt.min || remaining > tt.max {
+			t.Errorf("Header '%s': expected a timeout between %v and %v, got %v", tt.header, tt.min, tt.max, remaining)
+		}
+	}
+}
+
+// TestHeaderTimeoutRestoresRequest ensures middleware running after the chain doesn't see the cancelled deadline.
+func TestHeaderTimeoutRestoresRequest(t *testing.T) {
+	var after error
+	app := New()
+	app.Use(func(c *Context) {
+		c.Next()
+		after = c.Err()
+	})
+	app.Use(HeaderTimeout("X-Request-Timeout", time.Second))
+	app.handle("GET", "/", func(c *Context) {})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if after != nil {
+		t.Errorf("Expected the original context after the chain, got '%v'", after)
+	}
+}
+
+// TestHeaderTimeoutNoMax ensures no deadline is set without a header when max is 0.
+func TestHeaderTimeoutNoMax(t *testing.T) {
+	app := New()
+	app.Use(HeaderTimeout("X-Request-Timeout", 0))
+
+	hasDeadline := true
+	app.handle("GET", "/", func(c *Context) {
+		_, hasDeadline = c.Request.Context().Deadline()
+	})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if hasDeadline {
+		t.Errorf("Expected no deadline without header and max")
+	}
+}
+
+// TestMethodTimeouts ensures per-method deadlines override the global one.
+func TestMethodTimeouts(t *testing.T) {
+	app := New()
+	app.Timeout(5 * time.Second)
+	app.MethodTimeouts(map[string]time.Duration{
+		"get":  1 * time.Second,
+		"POST": 30 * time.Second,
+	})
+
+	var remaining time.Duration
+	handler := func(c *Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			t.Fatalf("Expected a deadline on the request context")
+		}
+		remaining = time.Until(deadline)
+		c.String(http.StatusOK, "ok")
+	}
+	app.handle("GET", "/", handler)
+	app.handle("POST", "/", handler)
+	app.handle("PUT", "/", handler)
+
+	tests := []struct {
+		method string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"GET", 0, 1 * time.Second},
+		{"POST", 29 * time.Second, 30 * time.Second},
+		{"PUT", 4 * time.Second, 5 * time.Second}, // global default
+	}
+
+	for _, tt := range tests {
+		app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/", nil))
+		if remaining <= tt.min || remaining > tt.max {
+			t.Errorf("%s: expected a deadline in (%v, %v], got %v", tt.method, tt.min, tt.max, remaining)
+		}
+	}
+}
+
+// TestBudget ensures an incoming deadline shortens the timeout and is forwarded upstream.
+func TestBudget(t *testing.T) {
+	var forwarded string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get(DeadlineHeader)
+	}))
+	defer upstream.Close()
+
+	app := New()
+	app.Use(Budget(30 * time.Second))
+	var remaining time.Duration
+	app.handle("GET", "/", func(c *Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			t.Fatalf("Expected a deadline on the request context")
+		}
+		remaining = time.Until(deadline)
+
+		req, _ := http.NewRequest("GET", upstream.URL, nil)
+		resp, err := c.HTTPClient().Do(req)
+		if err != nil {
+			t.Fatalf("Expected the upstream call to succeed, got %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	request := func(deadline string) {
+		req := httptest.NewRequest("GET", "/", nil)
+		if deadline != "" {
+			req.Header.Set(DeadlineHeader, deadline)
+		}
+		app.mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	request(formatDeadline(time.Now().Add(2 * time.Second)))
+	if remaining <= 0 || remaining > 2*time.Second {
+		t.Errorf("Expected the incoming deadline to shorten the budget, got %v left", remaining)
+	}
+	if d, ok := parseDeadline(forwarded); !ok || time.Until(d) > 2*time.Second {
+		t.Errorf("Expected the remaining budget to be forwarded, got '%s'", forwarded)
+	}
+
+	request(formatDeadline(time.Now().Add(time.Hour)))
+	if remaining > 30*time.Second {
+		t.Errorf("Expected the deadline to be capped at 30s, got %v left", remaining)
+	}
+
+	request("soon")
+	if remaining <= 29*time.Second || remaining > 30*time.Second {
+		t.Errorf("Expected a malformed header to fall back to the cap, got %v left", remaining)
+	}
+}
+
+// TestBudgetRestoresRequest ensures middleware running after the chain doesn't see the cancelled deadline.
+func TestBudgetRestoresRequest(t *testing.T) {
+	var after error
+	app := New()
+	app.Use(func(c *Context) {
+		c.Next()
+		after = c.Err()
+	})
+	app.Use(Budget(time.Second))
+	app.handle("GET", "/", func(c *Context) {})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if after != nil {
+		t.Errorf("Expected the original context after the chain, got '%v'", after)
+	}
+}
@@ -0,0 +1,57 @@
+package onion
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ----------------------------------------------------
+// TRACE method
+// ----------------------------------------------------
+
+// AllowTRACE enables the TRACE method. It is off by default, since echoing
+// requests back is a classic cross-site tracing vector: every TRACE request
+// gets a 405, even if a route was registered for it (e.g. via Any).
+func (a *App) AllowTRACE(on bool) {
+	a.allowTrace = on
+}
+
+// sensitiveTraceHeaders are never echoed back by TraceHandler.
+var sensitiveTraceHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+}
+
+// TraceHandler echoes the request line and headers back as message/http,
+// leaving out credentials and cookies. Register it once TRACE is allowed:
+//
+//	app.AllowTRACE(true)
+//	app.Handle(http.MethodTrace, "/debug/trace", onion.TraceHandler())
+func TraceHandler() HandlerFunc {
+	return func(c *Context) {
+		r := c.Request
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %s %s\r\n", r.Method, r.URL.RequestURI(), r.Proto)
+		fmt.Fprintf(&b, "Host: %s\r\n", r.Host)
+
+		keys := make([]string, 0, len(r.Header))
+		for k := range r.Header {
+			if !sensitiveTraceHeaders[k] {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range r.Header[k] {
+				fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+			}
+		}
+
+		c.Response.Header().Set("Content-Type", "message/http")
+		c.String(http.StatusOK, b.String())
+	}
+}
@@ -0,0 +1,51 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestTraceRejectedByDefault ensures TRACE gets a 405 even when a route was registered for it.
+func TestTraceRejectedByDefault(t *testing.T) {
+	app := New()
+	app.UseRoutes(NewGroup("echo").Any("", func(c *Context) {
+		c.String(http.StatusOK, "echo")
+	}).Routes())
+
+	req := httptest.NewRequest("TRACE", "/echo", nil)
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code 405, got %d", rec.Code)
+	}
+	if allow := rec.Header().Get("Allow"); strings.Contains(allow, "TRACE") {
+		t.Errorf("Expected TRACE to be left out of Allow, got '%s'", allow)
+	}
+}
+
+// TestTraceAllowed ensures TRACE works once enabled, without echoing credentials.
+func TestTraceAllowed(t *testing.T) {
+	app := New()
+	app.AllowTRACE(true)
+	app.Handle(http.MethodTrace, "/trace", TraceHandler())
+
+	req := httptest.NewRequest("TRACE", "/trace", nil)
+	req.Header.Set("X-Debug", "yes")
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "TRACE /trace HTTP/1.1") || !strings.Contains(body, "X-Debug: yes") {
+		t.Errorf("Expected the request to be echoed, got '%s'", body)
+	}
+	if strings.Contains(body, "secret") {
+		t.Errorf("Expected Authorization not to be echoed, got '%s'", body)
+	}
+}
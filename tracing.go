@@ -0,0 +1,79 @@
+package onion
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Distributed tracing hooks
+// ----------------------------------------------------
+
+// SetTraceID records the distributed trace this request belongs to. The ID
+// is attached as the "trace_id" log field, added to error responses from the
+// default error handler and to Recovery's panic logs, so a failure can be
+// found in the tracing backend. Tracing middleware (OpenTelemetry or not)
+// calls it once it has extracted or started a trace.
+func (c *Context) SetTraceID(id string) *Context {
+	c.traceID = id
+	return c.WithField("trace_id", id)
+}
+
+// TraceID returns the ID set with SetTraceID, or "".
+func (c *Context) TraceID() string {
+	return c.traceID
+}
+
+// TraceParent is a minimal W3C Trace Context middleware, and an example of
+// how to plug a tracer into the hooks above. It continues the trace from the
+// client's "traceparent" header, or starts a new one if the header is missing
+// or malformed, then answers with a "traceresponse" header carrying the trace
+// ID and this server's span ID.
+//
+// It doesn't record or export spans; use a tracing SDK for that.
+func TraceParent() HandlerFunc {
+	return func(c *Context) {
+		traceID, flags, ok := parseTraceParent(c.Request.Header.Get("traceparent"))
+		if !ok {
+			traceID, flags = randomHex(16), "00"
+		}
+		c.SetTraceID(traceID)
+		c.Response.Header().Set("traceresponse", "00-"+traceID+"-"+randomHex(8)+"-"+flags)
+	}
+}
+
+// parseTraceParent extracts the trace ID and flags from a version 00
+// traceparent: "00-<32 hex trace id>-<16 hex parent id>-<2 hex flags>".
+func parseTraceParent(h string) (traceID, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return "", "", false
+	}
+	if !isLowerHex(parts[1], 32) || !isLowerHex(parts[2], 16) || !isLowerHex(parts[3], 2) {
+		return "", "", false
+	}
+	// All-zero IDs are invalid per the spec
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", "", false
+	}
+	return parts[1], parts[3], true
+}
+
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
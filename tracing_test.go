@@ -0,0 +1,50 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestTraceParent ensures an incoming traceparent reaches the logs, errors and response header.
+func TestTraceParent(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.Use(Logger(LoggerConfig{}))
+	app.Use(TraceParent())
+	app.handle("GET", "/books/:id", func(c *Context) {
+		c.Error(NewHTTPError(http.StatusNotFound))
+	})
+
+	req := httptest.NewRequest("GET", "/books/1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, req)
+
+	resp := rec.Header().Get("traceresponse")
+	if !strings.HasPrefix(resp, "00-"+traceID+"-") || !strings.HasSuffix(resp, "-01") {
+		t.Errorf("Expected the trace ID in traceresponse, got '%s'", resp)
+	}
+	if !strings.Contains(logger.String(), "trace_id="+traceID) {
+		t.Errorf("Expected the trace ID in the log line, got '%s'", logger.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"trace_id":"`+traceID+`"`) {
+		t.Errorf("Expected the trace ID in the error body, got '%s'", rec.Body.String())
+	}
+}
+
+// TestTraceParentInvalid ensures a malformed header starts a new trace.
+func TestTraceParentInvalid(t *testing.T) {
+	for _, h := range []string{"", "garbage", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		c, _ := NewTestContext("GET", "/", nil, WithTestHeader("traceparent", h))
+		TraceParent()(c)
+
+		if !isLowerHex(c.TraceID(), 32) || strings.Trim(c.TraceID(), "0") == "" {
+			t.Errorf("traceparent %q: expected a fresh trace ID, got '%s'", h, c.TraceID())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package onion
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// ----------------------------------------------------
+// Response transformers
+// ----------------------------------------------------
+
+// DefaultMaxTransformSize is the default cap on buffered response bodies.
+const DefaultMaxTransformSize = 1 << 20 // 1MB
+
+// ResponseTransformer rewrites a response body before it's sent.
+type ResponseTransformer func(c *Context, body []byte) []byte
+
+type responseTransformer struct {
+	fn    ResponseTransformer
+	types map[string]bool
+}
+
+// UseResponseTransformer registers fn to rewrite response bodies whose
+// Content-Type is one of contentTypes (parameters like charset are ignored),
+// "application/json" if none are given. Transformers run in registration
+// order, after the handler is done.
+//
+// Matching responses are held in memory until the handler returns, so they
+// lose streaming and cost their full size in memory. Bodies that grow past
+// MaxTransformSize, or that are flushed explicitly, are sent as they are.
+func (a *App) UseResponseTransformer(fn ResponseTransformer, contentTypes ...string) {
+	if len(contentTypes) == 0 {
+		contentTypes = []string{"application/json"}
+	}
+	a.transformers = append(a.transformers, responseTransformer{fn: fn, types: toSet(contentTypes)})
+}
+
+// MaxTransformSize sets how many bytes a response may reach and still be
+// transformed. 0 restores DefaultMaxTransformSize.
+func (a *App) MaxTransformSize(n int) {
+	if n <= 0 {
+		n = DefaultMaxTransformSize
+	}
+	a.maxTransformSize = n
+}
+
+// transformWriter buffers matching responses so the transformers can run on
+// the whole body. Anything else passes straight through.
+type transformWriter struct {
+	http.ResponseWriter
+	c            *Context
+	transformers []responseTransformer
+	status       int
+	buf          bytes.Buffer
+	decided      bool // set once we know whether we buffer
+	passing      bool // writing straight through
+}
+
+// matching returns the transformers that apply to the response content type.
+func (w *transformWriter) matching() []responseTransformer {
+	mt, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	var out []responseTransformer
+	for _, t := range w.transformers {
+		if t.types[mt] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (w *transformWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	if w.passing {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *transformWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.decided {
+		w.decided = true
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		if len(w.matching()) == 0 {
+			w.passThrough()
+		}
+	}
+	if w.passing {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > w.c.app.maxTransformSize {
+		// Too big to hold on to: send what we have and stop buffering
+		w.passThrough()
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush gives up on transforming: the caller wants bytes on the wire now.
+func (w *transformWriter) Flush() {
+	if !w.passing {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.passThrough()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (w *transformWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// passThrough sends the status and anything buffered, then stops buffering.
+func (w *transformWriter) passThrough() {
+	w.decided, w.passing = true, true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// finish runs the transformers on the buffered body and sends the response.
+func (w *transformWriter) finish() {
+	if w.passing || w.status == 0 {
+		return
+	}
+	body := w.buf.Bytes()
+	if w.decided {
+		for _, t := range w.matching() {
+			body = t.fn(w.c, body)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
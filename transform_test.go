@@ -0,0 +1,58 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestResponseTransformerEnvelope ensures JSON responses are wrapped and other content types are left alone.
+func TestResponseTransformerEnvelope(t *testing.T) {
+	app := New()
+	app.UseResponseTransformer(func(c *Context, body []byte) []byte {
+		return []byte(`{"data":` + strings.TrimSpace(string(body)) + `}`)
+	})
+	app.handle("GET", "/json", func(c *Context) {
+		c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+	app.handle("GET", "/text", func(c *Context) {
+		c.String(http.StatusOK, "plain")
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/json", nil))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected status code 201, got %d", rec.Code)
+	}
+	if rec.Body.String() != `{"data":{"id":1}}` {
+		t.Errorf("Expected enveloped body, got '%s'", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Length") != "17" {
+		t.Errorf("Expected Content-Length 17, got '%s'", rec.Header().Get("Content-Length"))
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/text", nil))
+	if rec.Body.String() != "plain" {
+		t.Errorf("Expected text to pass through, got '%s'", rec.Body.String())
+	}
+}
+
+// TestResponseTransformerSizeCap ensures bodies over the cap are sent untransformed.
+func TestResponseTransformerSizeCap(t *testing.T) {
+	app := New()
+	app.MaxTransformSize(8)
+	app.UseResponseTransformer(func(c *Context, body []byte) []byte {
+		return []byte("transformed")
+	})
+	app.handle("GET", "/big", func(c *Context) {
+		c.JSON(http.StatusOK, []int{1, 2, 3, 4, 5, 6, 7, 8})
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/big", nil))
+	if !strings.HasPrefix(rec.Body.String(), "[1,2,3") {
+		t.Errorf("Expected the original body past the cap, got '%s'", rec.Body.String())
+	}
+}
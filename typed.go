@@ -0,0 +1,36 @@
+package onion
+
+import "fmt"
+
+// ----------------------------------------------------
+// Typed store accessors
+// ----------------------------------------------------
+
+// Get returns the value stored under key as a T. ok is false if the key is
+// missing or holds another type:
+//
+//	user, ok := onion.Get[*User](c, "user")
+func Get[T any](c *Context, key string) (T, bool) {
+	v, ok := c.store[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := v.(T)
+	return t, ok
+}
+
+// MustGet is like Get but panics if the key is missing or holds another type.
+// Use it where a middleware guarantees the value, e.g. behind auth.
+func MustGet[T any](c *Context, key string) T {
+	v, ok := c.store[key]
+	if !ok {
+		panic(fmt.Sprintf("onion: key %q not set on context", key))
+	}
+	t, ok := v.(T)
+	if !ok {
+		var zero T
+		panic(fmt.Sprintf("onion: key %q holds %T, not %T", key, v, zero))
+	}
+	return t
+}
@@ -0,0 +1,52 @@
+package onion
+
+import (
+	"strings"
+	"testing"
+)
+
+type testUser struct {
+	Name string
+}
+
+// TestTypedGet covers hits, misses and wrong types.
+func TestTypedGet(t *testing.T) {
+	c := &Context{}
+	c.Set("user", &testUser{Name: "alice"})
+	c.Set("count", 3)
+
+	if u, ok := Get[*testUser](c, "user"); !ok || u.Name != "alice" {
+		t.Errorf("Expected user 'alice', got %v, %v", u, ok)
+	}
+	if _, ok := Get[*testUser](c, "missing"); ok {
+		t.Errorf("Expected a miss for an unknown key")
+	}
+	if _, ok := Get[string](c, "count"); ok {
+		t.Errorf("Expected a miss for the wrong type")
+	}
+	if n := MustGet[int](c, "count"); n != 3 {
+		t.Errorf("Expected 3, got %d", n)
+	}
+}
+
+// TestMustGetPanics ensures MustGet panics with a clear message.
+func TestMustGetPanics(t *testing.T) {
+	c := &Context{}
+	c.Set("count", 3)
+
+	tests := map[string]string{
+		"missing": `key "missing" not set`,
+		"count":   `key "count" holds int, not string`,
+	}
+	for key, want := range tests {
+		func() {
+			defer func() {
+				msg, _ := recover().(string)
+				if !strings.Contains(msg, want) {
+					t.Errorf("Expected panic containing '%s', got '%s'", want, msg)
+				}
+			}()
+			MustGet[string](c, key)
+		}()
+	}
+}
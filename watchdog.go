@@ -0,0 +1,150 @@
+package onion
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------
+// Handler watchdog
+// ----------------------------------------------------
+
+// HandlerWatchdog runs every route handler in its own goroutine and answers
+// 503 if it hasn't returned within d, whether or not the handler watches its
+// context (the request context is cancelled too, for those that do). 0 disables it.
+//
+// Tradeoffs, since Go can't kill a goroutine:
+//   - a runaway handler is abandoned, not stopped: it keeps its goroutine and
+//     memory until it returns on its own, so a handler stuck forever leaks;
+//   - handler output is buffered and only sent once it returns, so streaming
+//     and c.Flush don't work under the watchdog;
+//   - the handler runs on its own copy of the Context, merged back if it
+//     returns in time; after the watchdog fires, its writes and changes to c
+//     (Set, WithField, ...) are dropped. Values it shares with the rest of
+//     the request (pointers in c's store, the *http.Request) are still
+//     shared, so it must not mutate those.
+//
+// Middlewares are not covered, only the route handler.
+func (a *App) HandlerWatchdog(d time.Duration) {
+	a.watchdog = d
+}
+
+// withWatchdog wraps a route handler as described in HandlerWatchdog.
+func (a *App) withWatchdog(key routeKey, handler HandlerFunc, d time.Duration) HandlerFunc {
+	return func(c *Context) {
+		orig := c.Response
+		tw := &timeoutWriter{header: make(http.Header)}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		hc := c.detached(tw)
+		hc.Request = c.Request.WithContext(ctx)
+
+		done := make(chan struct{})
+		var panicVal interface{}
+		go func() {
+			defer func() {
+				panicVal = recover()
+				close(done)
+			}()
+			handler(hc)
+		}()
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+			// Keep the caller's request, not the one with the watchdog's deadline
+			hc.Response, hc.writer, hc.Request = orig, c.writer, c.Request
+			*c = *hc
+			if panicVal != nil {
+				panic(panicVal)
+			}
+			tw.flushTo(orig)
+		case <-timer.C:
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+
+			a.logger.Printf("onion: %s exceeded the %s watchdog, handler abandoned", key, d)
+			orig.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			orig.WriteHeader(http.StatusServiceUnavailable)
+			orig.Write([]byte("Service Unavailable"))
+		}
+	}
+}
+
+// detached returns a copy of c writing to w, with its own maps, for a
+// handler that may outlive the request (see HandlerWatchdog).
+func (c *Context) detached(w http.ResponseWriter) *Context {
+	hc := *c
+	hc.writer = newResponseWriter(w)
+	hc.Response = hc.writer
+	hc.store = maps.Clone(c.store)
+	hc.fields = maps.Clone(c.fields)
+	hc.meta = maps.Clone(c.meta)
+	hc.flashes = maps.Clone(c.flashes)
+	hc.errors = slices.Clone(c.errors)
+	hc.params = slices.Clone(c.params)
+	return &hc
+}
+
+// timeoutWriter buffers a handler's response until it's known whether the
+// handler made it in time. Writes after the deadline fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.status != 0 {
+		return
+	}
+	tw.status = code
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.status == 0 {
+		tw.status = http.StatusOK
+	}
+	return tw.buf.Write(b)
+}
+
+// flushTo copies the buffered response to w.
+func (tw *timeoutWriter) flushTo(w http.ResponseWriter) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	dst := w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	if tw.status == 0 {
+		if tw.buf.Len() == 0 {
+			return
+		}
+		tw.status = http.StatusOK
+	}
+	w.WriteHeader(tw.status)
+	w.Write(tw.buf.Bytes())
+}
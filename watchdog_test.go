@@ -0,0 +1,121 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHandlerWatchdog ensures a handler ignoring cancellation is abandoned with a 503.
+func TestHandlerWatchdog(t *testing.T) {
+	logger := &captureLogger{}
+	app := New()
+	app.SetLogger(logger)
+	app.HandlerWatchdog(50 * time.Millisecond)
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	app.handle("GET", "/stuck", func(c *Context) {
+		defer close(finished)
+		<-release // ignores c.Done() on purpose
+		c.String(http.StatusOK, "too late")
+	})
+	app.handle("GET", "/fast", func(c *Context) {
+		c.Response.Header().Set("X-Fast", "yes")
+		c.String(http.StatusCreated, "fast")
+	})
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/stuck", nil))
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the watchdog to fire quickly, took %v", elapsed)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code 503, got %d", rec.Code)
+	}
+	if !strings.Contains(logger.String(), "GET /stuck exceeded") {
+		t.Errorf("Expected the timeout to be logged, got '%s'", logger.String())
+	}
+
+	// The abandoned handler's late write must not reach the response
+	close(release)
+	<-finished
+	if strings.Contains(rec.Body.String(), "too late") {
+		t.Errorf("Expected late writes to be dropped, got '%s'", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/fast", nil))
+	if rec.Code != http.StatusCreated || rec.Body.String() != "fast" || rec.Header().Get("X-Fast") != "yes" {
+		t.Errorf("Expected the fast handler's response, got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
+
+// TestHandlerWatchdogDetached ensures an abandoned handler works on its own
+// copy of the Context, while one that returns in time has its changes kept
+// (run with -race).
+func TestHandlerWatchdogDetached(t *testing.T) {
+	app := New()
+	app.SetLogger(&captureLogger{})
+	app.HandlerWatchdog(20 * time.Millisecond)
+
+	finished := make(chan struct{})
+	var late, fast interface{}
+	app.Use(func(c *Context) {
+		c.Set("user", "ann")
+		c.WithField("user", "ann")
+		c.Next()
+		// Keep reading c while the abandoned handler writes its copy
+		for i := 0; i < 100; i++ {
+			late, _ = c.Get("late")
+			_ = formatFields(c.fields)
+		}
+		fast, _ = c.Get("fast")
+	})
+	app.handle("GET", "/stuck", func(c *Context) {
+		defer close(finished)
+		time.Sleep(40 * time.Millisecond)
+		for i := 0; i < 100; i++ {
+			c.Set("late", i)
+			c.WithField("late", i)
+		}
+	})
+	app.handle("GET", "/fast", func(c *Context) {
+		c.Set("fast", true)
+	})
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stuck", nil))
+	<-finished
+	if late != nil {
+		t.Errorf("Expected the abandoned handler's changes to be dropped, got %v", late)
+	}
+
+	app.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fast", nil))
+	if fast != true {
+		t.Errorf("Expected the handler's changes to be kept, got %v", fast)
+	}
+}
+
+// TestHandlerWatchdogRestoresRequest ensures middleware running after a handler that made it in time doesn't see the watchdog's cancelled context.
+func TestHandlerWatchdogRestoresRequest(t *testing.T) {
+	var after error
+	app := New()
+	app.HandlerWatchdog(time.Second)
+	app.Use(func(c *Context) {
+		c.Next()
+		after = c.Err()
+	})
+	app.handle("GET", "/", func(c *Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	rec := httptest.NewRecorder()
+	app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Body.String() != "ok" || after != nil {
+		t.Errorf("Expected 'ok' and the original context after the chain, got '%s' and '%v'", rec.Body.String(), after)
+	}
+}
@@ -0,0 +1,76 @@
+package onion
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+)
+
+// ----------------------------------------------------
+// Weighted handlers (canary releases)
+// ----------------------------------------------------
+
+// WeightedHandler pairs a handler with its share of the traffic.
+//
+// (A map[HandlerFunc]int would read nicer, but funcs can't be map keys.)
+type WeightedHandler struct {
+	Handler HandlerFunc
+	Weight  int
+}
+
+// Weighted returns a handler that sends each request to one of the choices at
+// random, in proportion to the weights. For a 90/10 canary:
+//
+//	onion.Weighted(
+//		onion.WeightedHandler{Handler: v1, Weight: 90},
+//		onion.WeightedHandler{Handler: v2, Weight: 10},
+//	)
+func Weighted(choices ...WeightedHandler) HandlerFunc {
+	total := weightTotal(choices)
+	return func(c *Context) {
+		pickWeighted(choices, rand.IntN(total))(c)
+	}
+}
+
+// WeightedSticky is like Weighted, but picks by a hash of the client IP, so a
+// given client keeps seeing the same version.
+func WeightedSticky(choices ...WeightedHandler) HandlerFunc {
+	total := weightTotal(choices)
+	return func(c *Context) {
+		h := fnv.New32a()
+		h.Write([]byte(c.ClientIP()))
+		pickWeighted(choices, int(h.Sum32()%uint32(total)))(c)
+	}
+}
+
+// GETWeighted registers a GET route split between several handlers, see Weighted.
+func (rg *RouteGroup) GETWeighted(pattern string, choices ...WeightedHandler) *RouteGroup {
+	return rg.GET(pattern, Weighted(choices...))
+}
+
+func weightTotal(choices []WeightedHandler) int {
+	total := 0
+	for _, ch := range choices {
+		if ch.Handler == nil {
+			panic("onion: nil handler in weighted choices")
+		}
+		if ch.Weight < 0 {
+			panic("onion: negative weight in weighted choices")
+		}
+		total += ch.Weight
+	}
+	if total <= 0 {
+		panic("onion: weighted choices need a positive total weight")
+	}
+	return total
+}
+
+// pickWeighted maps n in [0, total) to a choice.
+func pickWeighted(choices []WeightedHandler, n int) HandlerFunc {
+	for _, ch := range choices {
+		if n < ch.Weight {
+			return ch.Handler
+		}
+		n -= ch.Weight
+	}
+	return choices[len(choices)-1].Handler
+}
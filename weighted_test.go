@@ -0,0 +1,52 @@
+package onion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGETWeighted ensures traffic is split roughly according to the weights.
+func TestGETWeighted(t *testing.T) {
+	app := New()
+	app.UseRoutes(NewGroup("books").GETWeighted("",
+		WeightedHandler{Handler: func(c *Context) { c.String(http.StatusOK, "v1") }, Weight: 90},
+		WeightedHandler{Handler: func(c *Context) { c.String(http.StatusOK, "v2") }, Weight: 10},
+	).Routes())
+
+	const n = 10000
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, httptest.NewRequest("GET", "/books", nil))
+		counts[rec.Body.String()]++
+	}
+
+	if share := float64(counts["v2"]) / n; share < 0.07 || share > 0.13 {
+		t.Errorf("Expected about 10%% of requests on v2, got %.1f%% (%v)", share*100, counts)
+	}
+	if counts["v1"]+counts["v2"] != n {
+		t.Errorf("Expected every request to hit v1 or v2, got %v", counts)
+	}
+}
+
+// TestWeightedSticky ensures a client keeps getting the same handler.
+func TestWeightedSticky(t *testing.T) {
+	app := New()
+	app.handle("GET", "/", WeightedSticky(
+		WeightedHandler{Handler: func(c *Context) { c.String(http.StatusOK, "v1") }, Weight: 50},
+		WeightedHandler{Handler: func(c *Context) { c.String(http.StatusOK, "v2") }, Weight: 50},
+	))
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		rec := httptest.NewRecorder()
+		app.mux.ServeHTTP(rec, req)
+		seen[rec.Body.String()] = true
+	}
+	if len(seen) != 1 {
+		t.Errorf("Expected a single version for one client, got %v", seen)
+	}
+}
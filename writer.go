@@ -0,0 +1,69 @@
+package onion
+
+import "net/http"
+
+// ----------------------------------------------------
+// responseWriter (records status and size)
+// ----------------------------------------------------
+
+// responseWriter wraps http.ResponseWriter so we know what status was sent
+// and how many bytes were written, after the handler is done.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int64
+	written bool
+
+	// maxSize caps the body, see App.MaxResponseSize. 0 means unlimited.
+	maxSize  int64
+	tooLarge func()
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+	w.status = code
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		// Like net/http, but also for writers (recorders, wrappers) that wouldn't check
+		return 0, http.ErrBodyNotAllowed
+	}
+	if w.maxSize > 0 && w.size+int64(len(b)) > w.maxSize {
+		if w.tooLarge != nil {
+			w.tooLarge()
+		}
+		// Let net/http drop the connection, so the client sees a broken
+		// response rather than a silently truncated one
+		panic(http.ErrAbortHandler)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher. It commits the headers if nothing was written yet.
+func (w *responseWriter) Flush() {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}